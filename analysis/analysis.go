@@ -0,0 +1,471 @@
+// Package analysis finds the possible values of the cells and of the data pointer of a BF
+// program by abstract interpretation, without running it. Each cell and the pointer are
+// tracked as an interval, and the intervals that keep growing around a loop are widened to
+// their limits, so the analysis ends on any program.
+//
+// It reports the commands that may overflow or underflow a cell, the loops that never run
+// or never end, and the moves that always take the pointer left of the first cell. The
+// results hold on every run, whatever the inputs, but some findings may only happen on
+// paths that no run takes.
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+const (
+	// widenAfter is the number of passes over a loop before its ranges are widened.
+	widenAfter = 2
+	// maxUnroll is the number of iterations of a loop followed one by one, while its
+	// cell is known exactly.
+	maxUnroll = 1 << 10
+	// unrollBudget is the number of loop iterations followed one by one over the whole
+	// analysis.
+	unrollBudget = 1 << 14
+)
+
+// Config is the machine the program is analyzed for.
+type Config struct {
+	Bits   int  // cell width in bits, 8 when zero
+	Signed bool // cells hold signed values
+}
+
+// Cells returns the range of the values of a cell.
+func (c Config) Cells() Interval {
+	bits := c.Bits
+	if bits == 0 {
+		bits = 8
+	}
+	if c.Signed {
+		return Interval{-1 << (bits - 1), 1<<(bits-1) - 1}
+	}
+	return Interval{0, 1<<bits - 1}
+}
+
+// Kind is a kind of finding.
+type Kind string
+
+const (
+	Overflow      Kind = "overflow"       // '+' on a cell at its highest value
+	Underflow     Kind = "underflow"      // '-' on a cell at its lowest value
+	ZeroLoop      Kind = "zero-loop"      // a loop that never runs, since its cell is always zero
+	InfiniteLoop  Kind = "infinite-loop"  // a loop that never ends once it is entered
+	NegativeIndex Kind = "negative-index" // '<' on the first cell
+)
+
+// Finding is something the analysis found at a position of the source.
+type Finding struct {
+	Kind    Kind
+	Certain bool // it happens each time the position is reached, not only on some paths
+	Pos     bf.Position
+	Message string
+}
+
+// String returns the finding in the line:column: message form.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Pos, f.Message)
+}
+
+// Result is the result of the analysis of a program.
+type Result struct {
+	Findings []Finding // ordered by position
+	// End is false when the program never ends, or always fails.
+	End bool
+	// Pointer is the range of the data pointer at the end of the program.
+	Pointer Interval
+}
+
+// Safe reports whether no run can overflow or underflow a cell, or move the pointer left of
+// the first cell.
+func (r *Result) Safe() bool {
+	for _, f := range r.Findings {
+		switch f.Kind {
+		case Overflow, Underflow, NegativeIndex:
+			return false
+		}
+	}
+	return true
+}
+
+// analyzer holds the findings of the analysis.
+type analyzer struct {
+	f       *syntax.File
+	cells   Interval
+	report  bool // findings are reported, on the last pass over each loop
+	reaches map[reachKey]*reach
+	budget  int // iterations left to follow one by one
+}
+
+type reachKey struct {
+	off  int
+	kind Kind
+}
+
+// reach sums up the times a command or a loop was reached, for a kind of finding.
+type reach struct {
+	total int
+	may   int      // reaches where it may happen
+	must  int      // reaches where it always happens
+	ptr   Interval // the data pointer, over all reaches
+	v     Interval // the current cell, over all reaches
+}
+
+// Analyze analyzes src, for a machine with cells as described by c. It returns an error
+// if src does not parse.
+func Analyze(src []byte, c Config) (*Result, error) {
+	f, err := syntax.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analyzer{f: f, cells: c.Cells(), report: true, reaches: make(map[reachKey]*reach), budget: unrollBudget}
+	s := &state{cells: make(map[int64]Interval)}
+	s = a.block(f.Nodes, s)
+
+	r := &Result{End: !s.dead, Pointer: s.ptr}
+	for k, re := range a.reaches {
+		if f, ok := a.finding(k, re); ok {
+			r.Findings = append(r.Findings, f)
+		}
+	}
+	sort.Slice(r.Findings, func(i, j int) bool {
+		return r.Findings[i].Pos.Offset < r.Findings[j].Pos.Offset
+	})
+	return r, nil
+}
+
+func (a *analyzer) pos(off int) bf.Position {
+	line, col := a.f.Position(off)
+	return bf.Position{Offset: off, Line: line, Column: col}
+}
+
+// reached records that the command or loop at off was reached, on the reporting pass, and
+// whether the finding of kind k may or must happen there, with the data pointer in ptr
+// and the current cell in v.
+func (a *analyzer) reached(off int, k Kind, may, must bool, ptr, v Interval) {
+	if !a.report {
+		return
+	}
+
+	key := reachKey{off, k}
+	re, ok := a.reaches[key]
+	if !ok {
+		re = &reach{ptr: ptr, v: v}
+		a.reaches[key] = re
+	}
+	re.total++
+	re.ptr = re.ptr.join(ptr)
+	re.v = re.v.join(v)
+	if may {
+		re.may++
+	}
+	if must {
+		re.must++
+	}
+}
+
+// finding returns the finding of the reaches re, if any.
+func (a *analyzer) finding(k reachKey, re *reach) (Finding, bool) {
+	f := Finding{Kind: k.kind, Certain: re.must == re.total, Pos: a.pos(k.off)}
+	if re.may == 0 || k.kind == ZeroLoop && !f.Certain {
+		return f, false
+	}
+
+	switch k.kind {
+	case Overflow:
+		if f.Certain {
+			f.Message = fmt.Sprintf("'+' always overflows %s, which is %s", cell(re.ptr), re.v)
+		} else {
+			f.Message = fmt.Sprintf("'+' may overflow %s, which is in %s", cell(re.ptr), re.v)
+		}
+	case Underflow:
+		if f.Certain {
+			f.Message = fmt.Sprintf("'-' always underflows %s, which is %s", cell(re.ptr), re.v)
+		} else {
+			f.Message = fmt.Sprintf("'-' may underflow %s, which is in %s", cell(re.ptr), re.v)
+		}
+	case ZeroLoop:
+		f.Message = fmt.Sprintf("the loop never runs: %s is always zero here", cell(re.ptr))
+	case InfiniteLoop:
+		if f.Certain {
+			f.Message = fmt.Sprintf("the loop never ends once entered: %s is never zero at its end", cell(re.ptr))
+		} else {
+			f.Message = "the loop may never end once entered"
+		}
+	case NegativeIndex:
+		if f.Certain {
+			f.Message = "'<' always moves the pointer left of the first cell"
+		} else {
+			f.Message = "'<' moves the pointer left of the first cell on some paths"
+		}
+	}
+	return f, true
+}
+
+// block runs nodes from the state s, which it updates, and returns the state after them.
+func (a *analyzer) block(nodes []syntax.Node, s *state) *state {
+	for _, n := range nodes {
+		if s.dead {
+			return s
+		}
+
+		switch n := n.(type) {
+		case *syntax.Command:
+			a.command(n, s)
+		case *syntax.Loop:
+			s = a.loop(n, s)
+		}
+	}
+	return s
+}
+
+func (a *analyzer) command(c *syntax.Command, s *state) {
+	switch c.Op {
+	case '>':
+		s.ptr = s.ptr.add(1)
+	case '<':
+		a.reached(c.Offset, NegativeIndex, s.ptr.Hi <= 0, s.ptr.Hi <= 0, s.ptr, s.read())
+		if s.ptr.Hi <= 0 {
+			s.dead = true
+			return
+		}
+		if s.ptr.Lo < 1 {
+			// the runs that go on are the ones on a later cell
+			s.ptr.Lo = 1
+		}
+		s.ptr = s.ptr.add(-1)
+	case '+':
+		v := s.read()
+		a.reached(c.Offset, Overflow, v.Hi == a.cells.Hi, v.Lo == a.cells.Hi, s.ptr, v)
+		s.write(a.inc)
+	case '-':
+		v := s.read()
+		a.reached(c.Offset, Underflow, v.Lo == a.cells.Lo, v.Hi == a.cells.Lo, s.ptr, v)
+		s.write(a.dec)
+	case ',':
+		s.write(func(Interval) Interval { return a.cells })
+	}
+}
+
+// inc adds one to the values of v, wrapping around.
+func (a *analyzer) inc(v Interval) Interval {
+	switch {
+	case v.Hi < a.cells.Hi:
+		return v.add(1)
+	case v.Lo == a.cells.Hi:
+		return single(a.cells.Lo)
+	}
+	return a.cells
+}
+
+// dec subtracts one from the values of v, wrapping around.
+func (a *analyzer) dec(v Interval) Interval {
+	switch {
+	case v.Lo > a.cells.Lo:
+		return v.add(-1)
+	case v.Hi == a.cells.Lo:
+		return single(a.cells.Hi)
+	}
+	return a.cells
+}
+
+// loop runs the loop l from the state s, and returns the state after it.
+func (a *analyzer) loop(l *syntax.Loop, s *state) *state {
+	v := s.read()
+	a.reached(l.Open, ZeroLoop, v == single(0), v == single(0), s.ptr, v)
+
+	s, done := a.unroll(l, s)
+	if done {
+		a.reached(l.Open, InfiniteLoop, false, false, s.ptr, s.read())
+		return s
+	}
+	if out, ok := a.counted(l, s); ok {
+		a.reached(l.Open, InfiniteLoop, false, false, s.ptr, s.read())
+		return out
+	}
+
+	return a.fixpoint(l, s)
+}
+
+// unroll follows the iterations of the loop l from the state s one by one, as long as its
+// cell is known exactly. It returns the state after the loop, or the state at the head of
+// the first iteration it did not follow, with done unset.
+func (a *analyzer) unroll(l *syntax.Loop, s *state) (_ *state, done bool) {
+	for i := 0; i < maxUnroll && a.budget > 0; i++ {
+		v := s.read()
+		if !s.ptr.Single() || !v.Single() {
+			return s, false
+		}
+		if v.Lo == 0 {
+			return s, true
+		}
+
+		a.budget--
+		s = a.block(l.Body, s.clone())
+		if s.dead {
+			return s, true
+		}
+	}
+	return s, false
+}
+
+// counted runs the loop l from the state s, if its body only moves and changes cells, ends
+// on the cell it started on, and takes one from that cell once on each iteration, like the
+// loops that copy or multiply a cell. Such a loop runs as many times as its cell holds, so
+// the ranges of the other cells are known from it.
+func (a *analyzer) counted(l *syntax.Loop, s *state) (*state, bool) {
+	type change struct {
+		c      *syntax.Command
+		off    int
+		before int64 // changes to the cell earlier in the iteration
+	}
+	var changes []change
+	deltas := make(map[int]int64) // change of each cell per iteration
+	d, min := 0, 0
+	for _, n := range l.Body {
+		c, ok := n.(*syntax.Command)
+		if !ok {
+			return nil, false
+		}
+		switch c.Op {
+		case '>':
+			d++
+		case '<':
+			d--
+			if d < min {
+				min = d
+			}
+		case '+', '-':
+			changes = append(changes, change{c, d, deltas[d]})
+			if c.Op == '+' {
+				deltas[d]++
+			} else {
+				deltas[d]--
+			}
+		case ',':
+			return nil, false
+		}
+	}
+	first := 0
+	for _, ch := range changes {
+		if ch.off == 0 {
+			first++
+		}
+	}
+	if d != 0 || deltas[0] != -1 || first != 1 {
+		return nil, false
+	}
+
+	if !s.ptr.Single() || s.ptr.Lo+int64(min) < 0 {
+		return nil, false
+	}
+	ptr := s.ptr.Lo
+	entry := s.read()
+	n := nonZero(entry) // iterations
+	if n.empty() || n.Lo < 1 {
+		return nil, false
+	}
+
+	for _, ch := range changes {
+		if ch.off == 0 {
+			continue
+		}
+		// the value before the change, on any iteration
+		v := s.get(ptr + int64(ch.off)).add(ch.before)
+		v = spread(v, deltas[ch.off], Interval{0, n.Hi - 1})
+		at := single(ptr + int64(ch.off))
+		if ch.c.Op == '+' {
+			a.reached(ch.c.Offset, Overflow, v.Hi >= a.cells.Hi, v.Lo >= a.cells.Hi, at, v)
+		} else {
+			a.reached(ch.c.Offset, Underflow, v.Lo <= a.cells.Lo, v.Hi <= a.cells.Lo, at, v)
+		}
+	}
+
+	out := s.clone()
+	for off, k := range deltas {
+		i := ptr + int64(off)
+		v := spread(s.get(i), k, n)
+		if v.Lo < a.cells.Lo || v.Hi > a.cells.Hi {
+			// wrapped around
+			v = a.cells
+		}
+		out.cells[i] = v
+	}
+	out.cells[ptr] = single(0)
+
+	if entry.Contains(0) {
+		skip := s.clone()
+		skip.refine(zero)
+		out = join(skip, out)
+	}
+	return out, true
+}
+
+// spread returns the values of v after k is added to it any number of times in times.
+func spread(v Interval, k int64, times Interval) Interval {
+	a, b := k*times.Lo, k*times.Hi
+	if a > b {
+		a, b = b, a
+	}
+	return Interval{v.Lo + a, v.Hi + b}
+}
+
+// fixpoint runs the loop l from the state s, until the state at its head no longer
+// changes, and returns the state after it.
+func (a *analyzer) fixpoint(l *syntax.Loop, s *state) *state {
+	head := s.clone()
+	head.normalize()
+
+	report := a.report
+	a.report = false
+	for i := 0; ; i++ {
+		body := head.clone()
+		body.refine(nonZero)
+		if !body.dead {
+			body = a.block(l.Body, body)
+		}
+
+		next := join(head, body)
+		if i >= widenAfter {
+			next = widen(head, next, a.cells)
+		}
+		if next.equal(head) {
+			break
+		}
+		head = next
+	}
+	a.report = report
+
+	// the last pass, with the final state at the head
+	if head.read() == single(0) {
+		// the loop is never entered
+		return head
+	}
+	body := head.clone()
+	body.refine(nonZero)
+	out := body
+	if !body.dead {
+		out = a.block(l.Body, body)
+	}
+
+	// the loop is left when it is skipped, or after an iteration
+	back := out.clone()
+	back.refine(zero)
+	stuck := !body.dead && back.dead
+	a.reached(l.Open, InfiniteLoop, stuck, stuck, out.ptr, out.read())
+	skip := s.clone()
+	skip.refine(zero)
+	return join(skip, back)
+}
+
+// cell describes the cells the pointer may be on.
+func cell(ptr Interval) string {
+	if ptr.Single() {
+		return fmt.Sprintf("cell %d", ptr.Lo)
+	}
+	return fmt.Sprintf("the cell in %s", ptr)
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestAnalyze(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name     string
+		src      string
+		config   Config
+		findings []string
+		safe     bool
+		end      bool
+		ptr      Interval
+	}{
+		{
+			name: "constant loops",
+			src:  "++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>>.<-.<.+++.------.--------.>>+.>++.",
+			safe: true, end: true, ptr: single(6),
+		},
+		{
+			name: "copy of an input",
+			src:  ",[>+>+<<-]>>[<<+>>-]",
+			safe: true, end: true, ptr: single(2),
+		},
+		{
+			name:     "double of an input",
+			src:      ",[>++<-]",
+			findings: []string{"1:4: '+' may overflow cell 1, which is in [0, 508]", "1:5: '+' may overflow cell 1, which is in [1, 509]"},
+			end:      true, ptr: single(0),
+		},
+		{
+			name:   "double of an input on 16 bits",
+			src:    ",[>++<-]",
+			config: Config{Bits: 16},
+			findings: []string{
+				"1:4: '+' may overflow cell 1, which is in [0, 131068]",
+				"1:5: '+' may overflow cell 1, which is in [1, 131069]",
+			},
+			end: true, ptr: single(0),
+		},
+		{
+			name:     "underflow",
+			src:      "-[-]",
+			findings: []string{"1:1: '-' always underflows cell 0, which is 0"},
+			end:      true, ptr: single(0),
+		},
+		{
+			name:   "signed cells",
+			src:    "-[+]",
+			config: Config{Signed: true},
+			safe:   true, end: true, ptr: single(0),
+		},
+		{
+			name:     "overflow on the last iteration",
+			src:      "+[+]",
+			findings: []string{"1:3: '+' may overflow cell 0, which is in [1, 255]"},
+			end:      true, ptr: single(0),
+		},
+		{
+			name:     "zero loop",
+			src:      "+[-]>[<+>-]",
+			findings: []string{"1:6: the loop never runs: cell 1 is always zero here"},
+			safe:     true, end: true, ptr: single(1),
+		},
+		{
+			name:     "infinite loop",
+			src:      ",[-+]",
+			findings: []string{"1:2: the loop never ends once entered: cell 0 is never zero at its end"},
+			safe:     true, end: true, ptr: single(0),
+		},
+		{
+			name:     "negative index",
+			src:      "+[>+<-]<",
+			findings: []string{"1:8: '<' always moves the pointer left of the first cell"},
+		},
+		{
+			name: "unknown pointer",
+			src:  ",[>,]<[<]",
+			safe: true, end: true, ptr: Interval{0, PosInf},
+		},
+	}
+
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			r, err := Analyze([]byte(test.src), test.config)
+			c.Assert(err, qt.IsNil)
+
+			var got []string
+			for _, f := range r.Findings {
+				got = append(got, f.String())
+			}
+			c.Assert(got, qt.DeepEquals, test.findings)
+			c.Assert(r.Safe(), qt.Equals, test.safe)
+			c.Assert(r.End, qt.Equals, test.end)
+			if r.End {
+				c.Assert(r.Pointer, qt.Equals, test.ptr)
+			}
+		})
+	}
+}
+
+func TestAnalyze_Certain(t *testing.T) {
+	c := qt.New(t)
+
+	r, err := Analyze([]byte("+[+]-[-]<"), Config{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(r.Findings, qt.HasLen, 3)
+	c.Assert(r.Findings[0].Kind, qt.Equals, Overflow)
+	c.Assert(r.Findings[0].Certain, qt.IsFalse)
+	c.Assert(r.Findings[1].Kind, qt.Equals, Underflow)
+	c.Assert(r.Findings[1].Certain, qt.IsTrue)
+	c.Assert(r.Findings[2].Kind, qt.Equals, NegativeIndex)
+	c.Assert(r.Findings[2].Certain, qt.IsTrue)
+
+	_, err = Analyze([]byte("[[]"), Config{})
+	c.Assert(err, qt.ErrorMatches, "loop openings/closing .* at offset 0")
+}
+
+func TestConfig_Cells(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(Config{}.Cells(), qt.Equals, Interval{0, 255})
+	c.Assert(Config{Bits: 8, Signed: true}.Cells(), qt.Equals, Interval{-128, 127})
+	c.Assert(Config{Bits: 32, Signed: true}.Cells(), qt.Equals, Interval{-1 << 31, 1<<31 - 1})
+}
@@ -0,0 +1,85 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+)
+
+// Bounds of unbounded intervals of the data pointer.
+const (
+	NegInf = math.MinInt64
+	PosInf = math.MaxInt64
+)
+
+// Interval is the range of integers from Lo to Hi, both inclusive. It is empty when Lo > Hi.
+type Interval struct {
+	Lo, Hi int64
+}
+
+func single(v int64) Interval {
+	return Interval{v, v}
+}
+
+// String returns the interval in the [lo, hi] form, or the value if it has only one.
+func (i Interval) String() string {
+	if i.Lo == i.Hi {
+		return bound(i.Lo)
+	}
+	return fmt.Sprintf("[%s, %s]", bound(i.Lo), bound(i.Hi))
+}
+
+func bound(v int64) string {
+	switch v {
+	case NegInf:
+		return "-inf"
+	case PosInf:
+		return "+inf"
+	}
+	return fmt.Sprint(v)
+}
+
+// Single reports whether the interval has exactly one value.
+func (i Interval) Single() bool {
+	return i.Lo == i.Hi
+}
+
+// Contains reports whether v is in the interval.
+func (i Interval) Contains(v int64) bool {
+	return i.Lo <= v && v <= i.Hi
+}
+
+func (i Interval) empty() bool {
+	return i.Lo > i.Hi
+}
+
+func (i Interval) join(j Interval) Interval {
+	if j.Lo < i.Lo {
+		i.Lo = j.Lo
+	}
+	if j.Hi > i.Hi {
+		i.Hi = j.Hi
+	}
+	return i
+}
+
+// add moves the interval by d, keeping the infinite bounds.
+func (i Interval) add(d int64) Interval {
+	if i.Lo != NegInf && i.Lo != PosInf {
+		i.Lo += d
+	}
+	if i.Hi != NegInf && i.Hi != PosInf {
+		i.Hi += d
+	}
+	return i
+}
+
+// widen returns next with the bounds that moved away from i set to lo and hi.
+func (i Interval) widen(next Interval, lo, hi int64) Interval {
+	if next.Lo < i.Lo {
+		next.Lo = lo
+	}
+	if next.Hi > i.Hi {
+		next.Hi = hi
+	}
+	return next
+}
@@ -0,0 +1,21 @@
+package analysis
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestInterval(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(single(3).String(), qt.Equals, "3")
+	c.Assert(Interval{0, PosInf}.String(), qt.Equals, "[0, +inf]")
+	c.Assert(Interval{NegInf, 2}.String(), qt.Equals, "[-inf, 2]")
+
+	c.Assert(Interval{1, 2}.join(Interval{5, 6}), qt.Equals, Interval{1, 6})
+	c.Assert(Interval{1, PosInf}.add(2), qt.Equals, Interval{3, PosInf})
+	c.Assert(Interval{1, 5}.widen(Interval{1, 6}, 0, 255), qt.Equals, Interval{1, 255})
+	c.Assert(Interval{1, 5}.widen(Interval{0, 5}, 0, 255), qt.Equals, Interval{0, 5})
+	c.Assert(Interval{1, 0}.empty(), qt.IsTrue)
+}
@@ -0,0 +1,151 @@
+package analysis
+
+// state is what is known of the machine at a point of the program: the range of the data
+// pointer and of each cell.
+type state struct {
+	dead  bool // the point is never reached
+	ptr   Interval
+	cells map[int64]Interval // cells known apart from the rest
+	rest  Interval           // every other cell
+}
+
+func (s *state) clone() *state {
+	c := *s
+	c.cells = make(map[int64]Interval, len(s.cells))
+	for k, v := range s.cells {
+		c.cells[k] = v
+	}
+	return &c
+}
+
+func (s *state) get(i int64) Interval {
+	if v, ok := s.cells[i]; ok {
+		return v
+	}
+	return s.rest
+}
+
+// read returns the range of the current cell.
+func (s *state) read() Interval {
+	if s.ptr.Single() {
+		return s.get(s.ptr.Lo)
+	}
+
+	v := s.rest
+	for k, c := range s.cells {
+		if s.ptr.Contains(k) {
+			v = v.join(c)
+		}
+	}
+	return v
+}
+
+// write applies f to the current cell. When the pointer is not known exactly, any cell
+// it may be on keeps its old values too.
+func (s *state) write(f func(Interval) Interval) {
+	if s.ptr.Single() {
+		s.cells[s.ptr.Lo] = f(s.get(s.ptr.Lo))
+		return
+	}
+
+	for k, c := range s.cells {
+		if s.ptr.Contains(k) {
+			s.cells[k] = c.join(f(c))
+		}
+	}
+	s.rest = s.rest.join(f(s.rest))
+}
+
+// refine narrows the current cell to the values in want, when the pointer is known
+// exactly. The state is dead if no value is left.
+func (s *state) refine(want func(Interval) Interval) {
+	if !s.ptr.Single() {
+		return
+	}
+	v := want(s.get(s.ptr.Lo))
+	if v.empty() {
+		s.dead = true
+		return
+	}
+	s.cells[s.ptr.Lo] = v
+}
+
+// nonZero drops zero from the current cell, as the loop body is entered.
+func nonZero(v Interval) Interval {
+	if v.Lo == 0 {
+		v.Lo = 1
+	}
+	if v.Hi == 0 {
+		v.Hi = -1
+	}
+	return v
+}
+
+// zero keeps only zero in the current cell, as the loop is left.
+func zero(v Interval) Interval {
+	if v.Contains(0) {
+		return single(0)
+	}
+	return Interval{1, 0}
+}
+
+// join returns the state reached from either s or t.
+func join(s, t *state) *state {
+	if s.dead {
+		return t.clone()
+	}
+	if t.dead {
+		return s.clone()
+	}
+
+	j := &state{ptr: s.ptr.join(t.ptr), rest: s.rest.join(t.rest), cells: make(map[int64]Interval)}
+	for k := range s.cells {
+		j.cells[k] = s.get(k).join(t.get(k))
+	}
+	for k := range t.cells {
+		j.cells[k] = s.get(k).join(t.get(k))
+	}
+	j.normalize()
+	return j
+}
+
+// widen returns next, with the ranges that grew since s set to their limits, so loops
+// are analyzed in a few passes.
+func widen(s, next *state, cells Interval) *state {
+	if s.dead || next.dead {
+		return next
+	}
+
+	w := next.clone()
+	w.ptr = s.ptr.widen(next.ptr, 0, PosInf)
+	w.rest = s.rest.widen(next.rest, cells.Lo, cells.Hi)
+	for k, v := range next.cells {
+		w.cells[k] = s.get(k).widen(v, cells.Lo, cells.Hi)
+	}
+	w.normalize()
+	return w
+}
+
+// normalize drops the cells known to be like the rest.
+func (s *state) normalize() {
+	for k, v := range s.cells {
+		if v == s.rest {
+			delete(s.cells, k)
+		}
+	}
+}
+
+func (s *state) equal(t *state) bool {
+	if s.dead || t.dead {
+		return s.dead == t.dead
+	}
+	if s.ptr != t.ptr || s.rest != t.rest || len(s.cells) != len(t.cells) {
+		return false
+	}
+	for k, v := range s.cells {
+		if w, ok := t.cells[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
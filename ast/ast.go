@@ -0,0 +1,99 @@
+// Package ast declares the types used to represent the syntax trees of BF sources, and
+// parses, walks and prints them.
+//
+// The tree keeps every byte of the source: the commands, the loops, the comments between
+// them, and the characters that could not be parsed, so printing a parsed File gives its
+// source back exactly. Positions are byte offsets in the source.
+package ast
+
+import "sort"
+
+// Pos is the byte offset of a character in the source, starting at 0.
+type Pos int
+
+// Node is a node of the syntax tree.
+type Node interface {
+	Pos() Pos // position of the first character of the node
+	End() Pos // position right after the last character of the node
+}
+
+type (
+	// Command is a single command, other than a loop bracket.
+	Command struct {
+		At Pos
+		Op byte
+	}
+
+	// Run is a sequence of the same command repeated, like +++ or >>, with nothing in
+	// between. Runs are only made with Config.Runs.
+	Run struct {
+		At    Pos
+		Op    byte
+		Count int // number of commands, at least 2
+	}
+
+	// Loop is a loop, with the positions of its brackets.
+	Loop struct {
+		Open, Close Pos
+		Body        []Node
+	}
+
+	// Custom is a command added to the language, from Config.Commands.
+	Custom struct {
+		At Pos
+		Op byte
+	}
+
+	// Comment is a sequence of characters that are not commands, whitespace included.
+	Comment struct {
+		At   Pos
+		Text string
+	}
+
+	// Bad is a character that could not be parsed: an unmatched bracket or a NUL
+	// character.
+	Bad struct {
+		At   Pos
+		Text string
+	}
+)
+
+func (c *Command) Pos() Pos { return c.At }
+func (r *Run) Pos() Pos     { return r.At }
+func (l *Loop) Pos() Pos    { return l.Open }
+func (c *Custom) Pos() Pos  { return c.At }
+func (c *Comment) Pos() Pos { return c.At }
+func (b *Bad) Pos() Pos     { return b.At }
+
+func (c *Command) End() Pos { return c.At + 1 }
+func (r *Run) End() Pos     { return r.At + Pos(r.Count) }
+func (l *Loop) End() Pos    { return l.Close + 1 }
+func (c *Custom) End() Pos  { return c.At + 1 }
+func (c *Comment) End() Pos { return c.At + Pos(len(c.Text)) }
+func (b *Bad) End() Pos     { return b.At + Pos(len(b.Text)) }
+
+// File is a parsed source. Its nodes cover the whole source, in order.
+type File struct {
+	Nodes []Node
+	Lines []int // offsets of the first character of each line
+	Size  int   // length of the source
+}
+
+func (f *File) Pos() Pos { return 0 }
+func (f *File) End() Pos { return Pos(f.Size) }
+
+// Position returns the line and the column, both starting at 1, of p. Columns count
+// bytes.
+func (f *File) Position(p Pos) (line, column int) {
+	l := sort.SearchInts(f.Lines, int(p)+1) - 1
+	return l + 1, int(p) - f.Lines[l] + 1
+}
+
+// IsCommand reports whether ch is one of the BF commands. Every other character is a comment.
+func IsCommand(ch byte) bool {
+	switch ch {
+	case '>', '<', '+', '-', '.', ',', '[', ']':
+		return true
+	}
+	return false
+}
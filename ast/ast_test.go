@@ -0,0 +1,30 @@
+package ast
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFile_Position(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := Parse([]byte("+\n\n->\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(f.Lines, qt.DeepEquals, []int{0, 2, 3, 6})
+
+	tests := []struct {
+		pos          Pos
+		line, column int
+	}{
+		{0, 1, 1},
+		{1, 1, 2},
+		{2, 2, 1},
+		{4, 3, 2},
+		{6, 4, 1},
+	}
+	for _, tt := range tests {
+		line, column := f.Position(tt.pos)
+		c.Assert([]int{line, column}, qt.DeepEquals, []int{tt.line, tt.column})
+	}
+}
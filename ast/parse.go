@@ -0,0 +1,186 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrIllegalCharNul   = errors.New("illegal character NUL")
+	ErrLoopDoesNotMatch = errors.New("loop openings/closing ([/]) count does not match")
+)
+
+// Error is an error found at a position of the source.
+type Error struct {
+	Pos Pos
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v at offset %d", e.Err, e.Pos)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList is the list of errors found in a source, ordered by position.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Unwrap returns the first error, so errors.Is works on the list.
+func (l ErrorList) Unwrap() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[0]
+}
+
+// Config controls how a source is parsed.
+type Config struct {
+	// Runs groups the repeated commands into Run nodes, instead of a Command node each.
+	Runs bool
+	// Commands are the characters of the commands added to the language, like '#' for the
+	// debugging command of the interpreter. Each byte is a command. The BF commands and
+	// NUL are ignored.
+	Commands string
+}
+
+// Parse parses src with the zero Config.
+func Parse(src []byte) (*File, error) {
+	return Config{}.Parse(src)
+}
+
+// Parse parses src. It returns an ErrorList with an error for each unmatched bracket and
+// NUL character. The File is always returned, with a Bad node for each of them, and the
+// body of an unclosed loop in place of the loop.
+func (c Config) Parse(src []byte) (*File, error) {
+	p := &parser{c: c, src: src, stack: []*Loop{nil}, comment: -1}
+	p.f = &File{Lines: []int{0}, Size: len(src)}
+
+	for i, ch := range src {
+		if ch == '\n' {
+			p.f.Lines = append(p.f.Lines, i+1)
+		}
+
+		switch {
+		case ch == 0:
+			p.errorAt(i, ErrIllegalCharNul)
+		case ch == '[':
+			p.flush(i)
+			p.stack = append(p.stack, &Loop{Open: Pos(i)})
+		case ch == ']':
+			if len(p.stack) == 1 {
+				p.errorAt(i, ErrLoopDoesNotMatch)
+				continue
+			}
+			p.flush(i)
+			l := p.stack[len(p.stack)-1]
+			l.Close = Pos(i)
+			p.stack = p.stack[:len(p.stack)-1]
+			p.add(l)
+		case IsCommand(ch):
+			p.flush(i)
+			p.command(i, ch)
+		case strings.IndexByte(c.Commands, ch) >= 0:
+			p.flush(i)
+			p.add(&Custom{At: Pos(i), Op: ch})
+		default:
+			p.text(i)
+		}
+	}
+	p.flush(len(src))
+
+	// unclosed loops: keep their bodies in place of them
+	for len(p.stack) > 1 {
+		l := p.stack[len(p.stack)-1]
+		p.stack = p.stack[:len(p.stack)-1]
+		p.errs = append(p.errs, &Error{Pos: l.Open, Err: ErrLoopDoesNotMatch})
+		p.add(&Bad{At: l.Open, Text: "["})
+		*p.body() = append(*p.body(), l.Body...)
+	}
+	p.f.Nodes = p.top
+
+	if len(p.errs) == 0 {
+		return p.f, nil
+	}
+	sort.Slice(p.errs, func(i, j int) bool { return p.errs[i].Pos < p.errs[j].Pos })
+	return p.f, p.errs
+}
+
+type parser struct {
+	c   Config
+	src []byte
+	f   *File
+
+	// stack of the loops being parsed, with the top level as a nil loop
+	stack   []*Loop
+	top     []Node
+	comment int // offset of the comment being read, -1 if none
+	errs    ErrorList
+}
+
+// body returns the nodes of the innermost loop being parsed.
+func (p *parser) body() *[]Node {
+	if l := p.stack[len(p.stack)-1]; l != nil {
+		return &l.Body
+	}
+	return &p.top
+}
+
+func (p *parser) add(n Node) {
+	*p.body() = append(*p.body(), n)
+}
+
+// text adds the character at i to the comment being read.
+func (p *parser) text(i int) {
+	if p.comment < 0 {
+		p.comment = i
+	}
+}
+
+// flush ends the comment being read, before the character at i.
+func (p *parser) flush(i int) {
+	if p.comment < 0 {
+		return
+	}
+	p.add(&Comment{At: Pos(p.comment), Text: string(p.src[p.comment:i])})
+	p.comment = -1
+}
+
+func (p *parser) errorAt(i int, err error) {
+	p.flush(i)
+	p.errs = append(p.errs, &Error{Pos: Pos(i), Err: err})
+	p.add(&Bad{At: Pos(i), Text: string(p.src[i : i+1])})
+}
+
+// command adds the command ch at i, to the run before it with Config.Runs.
+func (p *parser) command(i int, ch byte) {
+	body := p.body()
+	if p.c.Runs && len(*body) > 0 {
+		switch last := (*body)[len(*body)-1].(type) {
+		case *Command:
+			if last.Op == ch && int(last.End()) == i {
+				(*body)[len(*body)-1] = &Run{At: last.At, Op: ch, Count: 2}
+				return
+			}
+		case *Run:
+			if last.Op == ch && int(last.End()) == i {
+				last.Count++
+				return
+			}
+		}
+	}
+	p.add(&Command{At: Pos(i), Op: ch})
+}
@@ -0,0 +1,102 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// dump writes the nodes in a short form, with their positions.
+func dump(nodes []Node) string {
+	var b strings.Builder
+	for i, n := range nodes {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		switch n := n.(type) {
+		case *Command:
+			fmt.Fprintf(&b, "%c@%d", n.Op, n.At)
+		case *Run:
+			fmt.Fprintf(&b, "%c*%d@%d", n.Op, n.Count, n.At)
+		case *Custom:
+			fmt.Fprintf(&b, "custom(%c)@%d", n.Op, n.At)
+		case *Comment:
+			fmt.Fprintf(&b, "%q@%d", n.Text, n.At)
+		case *Bad:
+			fmt.Fprintf(&b, "bad(%q)@%d", n.Text, n.At)
+		case *Loop:
+			fmt.Fprintf(&b, "loop@%d-%d{%s}", n.Open, n.Close, dump(n.Body))
+		}
+	}
+	return b.String()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		c    Config
+		want string
+	}{{
+		name: "commands",
+		src:  "++ add\n[->+<]",
+		want: `+@0 +@1 " add\n"@2 loop@7-12{-@8 >@9 +@10 <@11}`,
+	}, {
+		name: "runs",
+		src:  "+++>>[--]+ +.",
+		c:    Config{Runs: true},
+		want: `+*3@0 >*2@3 loop@5-8{-*2@6} +@9 " "@10 +@11 .@12`,
+	}, {
+		name: "custom commands",
+		src:  "+#!+",
+		c:    Config{Commands: "#+"},
+		want: `+@0 custom(#)@1 "!"@2 +@3`,
+	}, {
+		name: "comments only",
+		src:  "hello\nworld",
+		want: `"hello\nworld"@0`,
+	}, {
+		name: "empty",
+		src:  "",
+		want: "",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			f, err := tt.c.Parse([]byte(tt.src))
+			c.Assert(err, qt.IsNil)
+			c.Assert(dump(f.Nodes), qt.Equals, tt.want)
+			c.Assert(int(f.End()), qt.Equals, len(tt.src))
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := Parse([]byte("]a[+\x00[[-]"))
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(err, qt.ErrorMatches, `loop openings/closing \(\[/\]\) count does not match at offset 0 \(and 3 more errors\)`)
+	c.Assert(errors.Is(err, ErrLoopDoesNotMatch), qt.IsTrue)
+
+	var errs ErrorList
+	c.Assert(errors.As(err, &errs), qt.IsTrue)
+	c.Assert(errs, qt.HasLen, 4)
+	for i, want := range []*Error{
+		{Pos: 0, Err: ErrLoopDoesNotMatch},
+		{Pos: 2, Err: ErrLoopDoesNotMatch},
+		{Pos: 4, Err: ErrIllegalCharNul},
+		{Pos: 5, Err: ErrLoopDoesNotMatch},
+	} {
+		c.Assert(errs[i].Pos, qt.Equals, want.Pos)
+		c.Assert(errs[i].Err, qt.Equals, want.Err)
+	}
+
+	// the unmatched brackets are bad nodes, and the bodies of the unclosed loops are kept
+	c.Assert(dump(f.Nodes), qt.Equals, `bad("]")@0 "a"@1 bad("[")@2 +@3 bad("\x00")@4 bad("[")@5 loop@6-8{-@7}`)
+}
@@ -0,0 +1,45 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Fprint writes the source of node to w. A parsed File is printed back exactly as its
+// source, comments and Bad nodes included.
+func Fprint(w io.Writer, node Node) error {
+	var b bytes.Buffer
+	write(&b, node)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func write(b *bytes.Buffer, node Node) {
+	switch n := node.(type) {
+	case *File:
+		for _, n := range n.Nodes {
+			write(b, n)
+		}
+	case *Loop:
+		b.WriteByte('[')
+		for _, n := range n.Body {
+			write(b, n)
+		}
+		b.WriteByte(']')
+	case *Command:
+		b.WriteByte(n.Op)
+	case *Run:
+		for i := 0; i < n.Count; i++ {
+			b.WriteByte(n.Op)
+		}
+	case *Custom:
+		b.WriteByte(n.Op)
+	case *Comment:
+		b.WriteString(n.Text)
+	case *Bad:
+		b.WriteString(n.Text)
+	default:
+		panic(fmt.Sprintf("ast.Fprint: unexpected node type %T", n))
+	}
+}
@@ -0,0 +1,52 @@
+package ast
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFprint(t *testing.T) {
+	test, err := os.ReadFile("../testdata/test.bf")
+	qt.Assert(t, err, qt.IsNil)
+
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"program", string(test)},
+		{"comments", "add two\n++ [->+<] # done\n\n"},
+		{"errors", "]x[+\x00[[-]"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		for _, conf := range []Config{{}, {Runs: true, Commands: "#"}} {
+			t.Run(tt.name, func(t *testing.T) {
+				c := qt.New(t)
+
+				f, _ := conf.Parse([]byte(tt.src))
+				var b bytes.Buffer
+				err := Fprint(&b, f)
+				c.Assert(err, qt.IsNil)
+				c.Assert(b.String(), qt.Equals, tt.src)
+			})
+		}
+	}
+}
+
+func TestFprint_Node(t *testing.T) {
+	c := qt.New(t)
+
+	l := &Loop{Body: []Node{
+		&Run{Op: '-', Count: 2},
+		&Comment{Text: " x "},
+		&Loop{Body: []Node{&Command{Op: '>'}}},
+	}}
+	var b bytes.Buffer
+	err := Fprint(&b, l)
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.String(), qt.Equals, "[-- x [>]]")
+}
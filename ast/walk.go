@@ -0,0 +1,53 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called for each node found by Walk. If the visitor w it
+// returns is not nil, Walk visits each of the children of the node with w, followed by a
+// call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree of node in depth-first order. It starts by calling
+// v.Visit(node), and node must not be nil.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *File:
+		walkList(v, n.Nodes)
+	case *Loop:
+		walkList(v, n.Body)
+	case *Command, *Run, *Custom, *Comment, *Bad:
+		// no children
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkList(v Visitor, nodes []Node) {
+	for _, n := range nodes {
+		Walk(v, n)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree of node in depth-first order. It calls f(node), and if f
+// returns true, it inspects each of the children of the node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
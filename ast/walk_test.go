@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestInspect(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := Config{Runs: true}.Parse([]byte("++[>[-]c]."))
+	c.Assert(err, qt.IsNil)
+
+	var b strings.Builder
+	Inspect(f, func(n Node) bool {
+		switch n := n.(type) {
+		case nil:
+			b.WriteString(")")
+		case *File:
+			b.WriteString("file(")
+		case *Loop:
+			b.WriteString("loop(")
+		default:
+			fmt.Fprintf(&b, "%T ", n)
+		}
+		return true
+	})
+	c.Assert(b.String(), qt.Equals,
+		"file(*ast.Run )loop(*ast.Command )loop(*ast.Command ))*ast.Comment ))*ast.Command ))")
+
+	// skip the children of the loops
+	var ops []byte
+	Inspect(f, func(n Node) bool {
+		switch n := n.(type) {
+		case *Command:
+			ops = append(ops, n.Op)
+		case *Loop:
+			ops = append(ops, '[')
+			return false
+		}
+		return true
+	})
+	c.Assert(string(ops), qt.Equals, "[.")
+}
+
+// depth counts the nodes at each depth.
+type depth struct {
+	level  int
+	counts map[int]int
+}
+
+func (d *depth) Visit(n Node) Visitor {
+	if n == nil {
+		return nil
+	}
+	d.counts[d.level]++
+	return &depth{level: d.level + 1, counts: d.counts}
+}
+
+func TestWalk(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := Parse([]byte("+[>[-]<]"))
+	c.Assert(err, qt.IsNil)
+
+	d := &depth{counts: map[int]int{}}
+	Walk(d, f)
+	c.Assert(d.counts, qt.DeepEquals, map[int]int{0: 1, 1: 2, 2: 3, 3: 1})
+}
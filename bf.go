@@ -4,12 +4,16 @@ package bf
 
 import (
 	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
+	"time"
 	"unsafe"
+
+	"github.com/thesoulless/bf/internal/syntax"
+	"github.com/thesoulless/bf/sourcemap"
 )
 
 var (
@@ -19,25 +23,18 @@ var (
 	ErrNoCommands       = errors.New("no commands to run")
 	ErrDuplicateCmd     = errors.New("duplicate command")
 	ErrNegativeIndex    = errors.New("array index can't be less than zero")
-	ErrIllegalCharNul   = errors.New("illegal character NUL")
-	ErrLoopDoesNotMatch = errors.New("loop openings/closing ([/]) count does not match")
-)
-
-const (
-	eof = -1 // end of file
+	ErrIllegalCharNul   = syntax.ErrIllegalCharNul
+	ErrLoopDoesNotMatch = syntax.ErrLoopDoesNotMatch
 )
 
-type offset struct {
-	offset     int // character offset
-	rdOffset   int // reading offset (position after current character)
-	lineOffset int // current line offset
-}
-
 // BF represents the interpreter of Brainfuck. It scans each command and runs it right away.
 // Since Go uses rune (int32) to represent character values, bf uses int32 for cell size, starting
-// with a 30K item backing array. The backing array will expand in case of a need so the bf be
+// with a small backing array. The backing array will expand in case of a need so the bf be
 // Turing complete.
 //
+// When every loop has a zero net pointer movement, the tape is allocated up front with the
+// exact number of cells the program can reach.
+//
 // On validation, it returns error on empty command set, and when loop beginning and endings
 // does not match.
 // On execution, it returns error on moving index to negative, and encountering a NUL character.
@@ -45,64 +42,108 @@ type BF struct {
 	src []byte // source
 	out io.Writer
 
-	c     int     // backing array limit counter
-	arr   []int32 // backing array
-	ptr   unsafe.Pointer
-	lpos  []offset // loop stack positions
-	ucmds map[rune]func(unsafe.Pointer)
+	c       int         // data pointer, index of the current cell
+	arr     []int32     // backing array
+	bounded bool        // the pointer stays in arr, so moves are not checked
+	lpos    []int       // loop stack, offsets of the open brackets
+	jump    map[int]int // matching bracket offsets, in both directions
+	cmds    []rune      // BF commands, less the removed ones
+	ucmds   map[rune]func(unsafe.Pointer)
 
 	// scanning state
-	ch      rune // current character
-	o       offset
+	pc      int       // offset of the next character to scan
+	lines   []int     // offsets of the first character of each line
 	inp     io.Reader // input (,) reader
 	inpscan *bufio.Scanner
+	err     error // error that stopped the program, returned again by every later run
+
+	// debugging state
+	intr  int32            // set by Interrupt, accessed atomically
+	bps   map[int]struct{} // breakpoints, keyed by source offset
+	hooks Hooks            // nil when no hooks are registered
+	debug io.Writer        // '#' output, nil when '#' is not a command
+	trace *tracer          // nil when not tracing
+
+	cycles *cycles // nil when not detecting infinite loops
+
+	smap *sourcemap.Map // nil without a source map
+
+	// statistics
+	steps    int64      // number of commands run
+	ops      [256]int64 // runs of each command
+	maxPtr   int
+	maxDepth int
+	inBytes  int64
+	outBytes int64
+	wall     time.Duration
+
+	watches []Watchpoint
+	watchID int        // last watchpoint ID
+	hits    []WatchHit // watchpoints triggered by the last command
+
+	history   bool
+	histLimit int
+	log       []undo   // undo log, the last command run last
+	replay    []string // input lines given back by reverted commands, the next one last
+	redo      int      // number of reverted commands, whose outputs are already written
 }
 
 // New creates a new BF. It returns error on reading from src, or validating commands.
 // It takes src as the source of commands, out as where to write the outputs, and
-// input as where it should read the inputs (, command)
-func New(src io.Reader, out io.Writer, input io.Reader) (*BF, error) {
+// input as where it should read the inputs (, command). Options are applied in order.
+func New(src io.Reader, out io.Writer, input io.Reader, opts ...Option) (*BF, error) {
 	insts, err := io.ReadAll(src)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &BF{src: insts, out: out, inp: input}
-	err = s.init()
+	neg, err := s.init()
 	if err != nil {
 		return nil, err
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if neg >= 0 {
+		// reported after the options, to give its position in the source map
+		return nil, nextErr(ErrNegativeIndex, s.position(neg))
+	}
 	return s, nil
 }
 
-func (b *BF) init() error {
-	b.ch = ' '
+// init sets up a BF for its source. It returns the offset of the '<' that moves the
+// pointer left of the first cell on every run, or -1.
+func (b *BF) init() (int, error) {
 	b.arr = make([]int32, size)
-	b.o.offset = 0
-	b.o.rdOffset = 0
-	b.o.lineOffset = 0
-	b.ptr = unsafe.Pointer(&b.arr[0])
+	b.pc = 0
 	b.inpscan = bufio.NewScanner(b.inp)
+	b.cmds = append([]rune(nil), defaultCms...)
 	b.ucmds = make(map[rune]func(unsafe.Pointer))
+	b.bps = make(map[int]struct{})
 
-	err := validate(b.src)
+	f, jump, err := validate(b.src)
 	if err != nil {
-		return err
+		return -1, err
 	}
+	b.jump = jump
 
-	o, err := b.next()
-	if err != nil {
-		return nextErr(err, o)
+	b.lines = []int{0}
+	for i, c := range b.src {
+		if c == '\n' {
+			b.lines = append(b.lines, i+1)
+		}
 	}
 
-	return nil
+	return b.bounds(f), nil
 }
 
 // AddCommand associate a function to a character. It returns error on overriding
 // current valid commands (defaults and user-defined). It passes the pointer to the
 // current array cell to the function.
 func (b *BF) AddCommand(cmd rune, f func(ptr unsafe.Pointer)) error {
-	for _, c := range defaultCms {
+	for _, c := range b.cmds {
 		if cmd == c {
 			return ErrDuplicateCmd
 		}
@@ -110,17 +151,22 @@ func (b *BF) AddCommand(cmd rune, f func(ptr unsafe.Pointer)) error {
 	if _, ok := b.ucmds[cmd]; ok {
 		return ErrDuplicateCmd
 	}
+	if cmd == '#' && b.debug != nil {
+		return ErrDuplicateCmd
+	}
 
 	b.ucmds[cmd] = f
 	return nil
 }
 
 // RemoveCommand removes a command from BF, it works for user-defined and also
-// the default commands
+// the default commands. Other BFs keep their commands.
 func (b *BF) RemoveCommand(cmd rune) {
-	for i, c := range defaultCms {
+	for i, c := range b.cmds {
 		if cmd == c {
-			defaultCms = append(defaultCms[0:i], defaultCms[i+1:]...)
+			b.cmds = append(b.cmds[0:i], b.cmds[i+1:]...)
+			// the pointer bounds were found with the command
+			b.bounded = false
 			return
 		}
 	}
@@ -128,161 +174,229 @@ func (b *BF) RemoveCommand(cmd rune) {
 	delete(b.ucmds, cmd)
 }
 
-func (b *BF) next() (offset, error) {
-	if b.o.rdOffset < len(b.src) {
-		b.o.offset = b.o.rdOffset
-		if b.ch == '\n' {
-			b.o.lineOffset = b.o.offset
-		}
-		nr, w := rune(b.src[b.o.rdOffset]), 1
-		if nr == 0 {
-			return b.o, ErrIllegalCharNul
+func (b *BF) isCommand(ch rune) bool {
+	for _, c := range b.cmds {
+		if ch == c {
+			return true
 		}
-		b.o.rdOffset += w
-		b.ch = nr
-	} else {
-		b.o.offset = len(b.src)
-		if b.ch == '\n' {
-			b.o.lineOffset = b.o.offset
+	}
+	if ch == '#' && b.debug != nil {
+		return true
+	}
+	_, ok := b.ucmds[ch]
+	return ok
+}
+
+// peek returns the offset of the next command at or after off, without
+// moving the scanner. It stops at a NUL character, so the caller can report it.
+func (b *BF) peek(off int) int {
+	for ; off < len(b.src); off++ {
+		ch := rune(b.src[off])
+		if ch == 0 || b.isCommand(ch) {
+			break
 		}
-		b.ch = eof
 	}
 
-	return offset{}, nil
+	return off
 }
 
-func (b *BF) skipWhitespace() (offset, error) {
-	for b.ch == ' ' || b.ch == '\t' || b.ch == '\n' || b.ch == '\r' {
-		if o, err := b.next(); err != nil {
-			return o, err
-		}
+// skipComments moves the scanner to the next command, skipping whitespace and
+// any other character that is not a command.
+func (b *BF) skipComments() error {
+	b.pc = b.peek(b.pc)
+	if b.pc < len(b.src) && b.src[b.pc] == 0 {
+		return nextErr(ErrIllegalCharNul, b.position(b.pc))
 	}
 
-	return offset{}, nil
+	return nil
 }
 
-// Exec reads and executes each command until it reaches EOF
+// Exec reads and executes each command until it reaches EOF. It returns the errors of the
+// commands without their positions, like ErrNegativeIndex, so they can be compared with
+// ==. Use Continue to get them with their positions, as a *PositionError. Once a command
+// fails, the program stops on it, and Exec and the other runs return the same error.
 func (b *BF) Exec() error {
-	var res bytes.Buffer
-	for {
-		o, err := b.skipWhitespace()
-		if err != nil {
-			return nextErr(err, o)
-		}
+	start := time.Now()
+	defer func() { b.wall += time.Since(start) }()
 
-		// determine token value
-		ch := b.ch
-		o, err = b.next()
-		if err != nil {
-			return nextErr(err, o)
+	var err error
+	if b.observed() {
+		for err == nil {
+			_, err = b.next()
 		}
+	} else {
+		_, err = b.run(-1, false)
+	}
+	if err == io.EOF {
+		return nil
+	}
 
-		switch ch {
-		case '-':
-			*(*int32)(b.ptr)--
-		case '[':
-			// always push the position to the stack
-			b.lpos = append([]offset{
-				{
-					offset:     b.o.offset - 2,
-					rdOffset:   b.o.rdOffset - 2,
-					lineOffset: b.o.lineOffset,
-				},
-			}, b.lpos...)
-		case ']':
-			if len(b.lpos) == 0 || (b.lpos[0].offset == 0 && b.lpos[0].rdOffset == 0 && b.lpos[0].lineOffset == 0) {
-				return ErrLoopDoesNotMatch
-			}
-
-			// continue the loop of *ptr is not zero
-			if *(*int32)(b.ptr) != 0 {
-				// jump back, and read again
-				b.o = b.lpos[0]
-				o, err := b.next()
-				if err != nil {
-					return nextErr(err, o)
-				}
-			}
-
-			// always pop the loop stack
-			b.lpos = b.lpos[1:]
-		case '>':
-			b.c++
-			if b.c >= size {
-				// instead of returning error expand the backing array cap
-				size = len(b.arr) + size
-				b.arr = append(make([]int32, 0, size), b.arr...)
-			}
-			b.ptr = unsafe.Add(b.ptr, unsafe.Sizeof(b.arr[0]))
-		case '<':
-			b.c--
-			if b.c < 0 {
-				return ErrNegativeIndex
-			}
-			b.ptr = unsafe.Pointer(uintptr(b.ptr) - unsafe.Sizeof(b.arr[0]))
-		case '+':
-			*(*int32)(b.ptr)++
-		case ',':
-			b.inpscan.Scan()
-			text := b.inpscan.Text()
-			if text != "" {
-				i, err := strconv.ParseInt(text, 10, 32)
-				if err != nil {
-					return fmt.Errorf("invalid input: %v", err)
-				}
-				*(*int32)(b.ptr) = int32(i)
-			}
-		case '.':
-			res.WriteString(string(*(*int32)(b.ptr)))
+	var perr *PositionError
+	if errors.As(err, &perr) {
+		return perr.Err
+	}
+	return err
+}
 
-			// ignore unknown commands
+// step executes the command the scanner is on, and moves the scanner past it.
+func (b *BF) step() error {
+	at := b.pc
+	ch := rune(b.src[at])
+	b.pc++
+
+	switch ch {
+	case '-':
+		b.arr[b.c]--
+	case '[':
+		if b.arr[b.c] == 0 {
+			// skip the whole loop
+			b.pc = b.jump[at] + 1
+			break
+		}
+		b.lpos = append(b.lpos, at)
+		if len(b.lpos) > b.maxDepth {
+			b.maxDepth = len(b.lpos)
+		}
+		if b.hooks != nil {
+			b.hooks.OnLoopEnter(b.position(at))
+		}
+	case ']':
+		if len(b.lpos) == 0 {
+			return ErrLoopDoesNotMatch
 		}
 
-		if f, ok := b.ucmds[ch]; ok {
-			f(b.ptr)
+		// continue the loop if the current cell is not zero
+		if b.arr[b.c] != 0 {
+			b.pc = b.lpos[len(b.lpos)-1] + 1
+			break
 		}
 
-		if ch == eof {
+		open := b.lpos[len(b.lpos)-1]
+		b.lpos = b.lpos[:len(b.lpos)-1]
+		if b.hooks != nil {
+			b.hooks.OnLoopExit(b.position(open))
+		}
+	case '>':
+		b.c++
+		if !b.bounded && b.c >= len(b.arr) {
+			// instead of returning error expand the backing array
+			b.arr = append(b.arr, make([]int32, len(b.arr))...)
+		}
+		if b.c > b.maxPtr {
+			b.maxPtr = b.c
+		}
+		if b.hooks != nil {
+			b.hooks.OnPointerMove(b.c-1, b.c)
+		}
+	case '<':
+		if !b.bounded && b.c == 0 {
+			return ErrNegativeIndex
+		}
+		b.c--
+		if b.hooks != nil {
+			b.hooks.OnPointerMove(b.c+1, b.c)
+		}
+	case '+':
+		b.arr[b.c]++
+	case ',':
+		text := b.readInput()
+		if text != "" {
+			i, err := strconv.ParseInt(text, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid input: %v", err)
+			}
+			b.arr[b.c] = int32(i)
+			if b.hooks != nil {
+				b.hooks.OnInput(b.arr[b.c])
+			}
+		}
+	case '.':
+		if b.redo > 0 {
+			// written before the command was reverted
 			break
 		}
+		n, err := io.WriteString(b.out, string(b.arr[b.c]))
+		b.outBytes += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed writing to the output: %v", err)
+		}
+		if b.hooks != nil {
+			b.hooks.OnOutput(b.arr[b.c])
+		}
+	case '#':
+		if b.debug != nil {
+			err := b.dump(at)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	_, err := b.out.Write(res.Bytes())
-	if err != nil {
-		return fmt.Errorf("failed writing to the output: %v", err)
+	if len(b.ucmds) > 0 {
+		if f, ok := b.ucmds[ch]; ok {
+			f(unsafe.Pointer(&b.arr[b.c]))
+		}
 	}
 
 	return nil
 }
 
-// validate the commands source. It returns error on empty command set, and when loop
-// beginning and endings does not match.
-func validate(src []byte) error {
-	s := string(src)
+// position converts a source offset to a Position.
+func (b *BF) position(off int) Position {
+	l := sort.SearchInts(b.lines, off+1) - 1
+	p := Position{
+		Offset: off,
+		Line:   l + 1,
+		Column: off - b.lines[l] + 1,
+	}
+	if b.smap != nil {
+		p.Orig = b.orig(p)
+	}
+	return p
+}
 
-	if s == "" {
-		return ErrNoCommands
+// validate the commands source. It returns error on empty command set, and when loop
+// beginning and endings does not match. On success, it returns the parsed source and the
+// offsets of the matching brackets of each loop.
+func validate(src []byte) (*syntax.File, map[int]int, error) {
+	if len(src) == 0 {
+		return nil, nil, ErrNoCommands
 	}
 
-	var c int
-	for _, r := range s {
-		if r == '[' {
-			c++
-			continue
+	f, err := syntax.Parse(src)
+	if err != nil {
+		var errs syntax.ErrorList
+		if !errors.As(err, &errs) {
+			return nil, nil, err
 		}
-
-		if r == ']' {
-			c--
+		// NUL characters are reported when the scanner reaches them
+		for _, e := range errs {
+			if e.Err == ErrLoopDoesNotMatch {
+				return nil, nil, ErrLoopDoesNotMatch
+			}
 		}
 	}
 
-	if c != 0 {
-		return ErrLoopDoesNotMatch
-	}
+	jump := make(map[int]int)
+	f.Inspect(func(n syntax.Node, _ []*syntax.Loop) bool {
+		if l, ok := n.(*syntax.Loop); ok {
+			jump[l.Open], jump[l.Close] = l.Close, l.Open
+		}
+		return true
+	})
 
-	return nil
+	return f, jump, nil
+}
+
+// parseValid parses a source that New accepted, for the reports made from its runs. New
+// only rejects unmatched brackets, and leaves the NUL characters to be reported when a
+// run reaches them. Parse reports them too, but leaves them out of the tree like the
+// comments, so every command and loop is in the returned File.
+func parseValid(src []byte) *syntax.File {
+	f, _ := syntax.Parse(src)
+	return f
 }
 
-func nextErr(err error, o offset) error {
-	return fmt.Errorf("%v at %d:%d (line:offset)", err, o.lineOffset, o.offset)
+func nextErr(err error, p Position) error {
+	return &PositionError{Pos: p, Err: err}
 }
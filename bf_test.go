@@ -2,7 +2,9 @@ package bf
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"strings"
 	"testing"
@@ -38,6 +40,19 @@ func TestBF_Run(t *testing.T) {
 		input := strings.NewReader(s)
 		wantErr := ErrNegativeIndex
 
+		// the first '<' always moves left of the first cell, so New reports it
+		_, err := New(input, io.Discard, nil)
+
+		c.Assert(err, qt.IsNotNil)
+		c.Assert(err, qt.ErrorIs, wantErr)
+		c.Assert(err, qt.ErrorMatches, "array index can't be less than zero at 1:9 \\(line:column\\)")
+	})
+
+	t.Run("negative index in a loop", func(t *testing.T) {
+		s := `++++++++[<]`
+		input := strings.NewReader(s)
+		wantErr := ErrNegativeIndex
+
 		var buf []byte
 		out := bytes.NewBuffer(buf)
 
@@ -50,6 +65,30 @@ func TestBF_Run(t *testing.T) {
 		c.Assert(err, qt.Equals, wantErr)
 	})
 
+	t.Run("negative index position", func(t *testing.T) {
+		s := `++++++++[>+<-]<`
+		input := strings.NewReader(s)
+
+		bfi, err := New(input, io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+		_, err = bfi.Continue()
+
+		var perr *PositionError
+		c.Assert(errors.As(err, &perr), qt.IsTrue)
+		c.Assert(perr.Err, qt.Equals, ErrNegativeIndex)
+		c.Assert(perr.Pos, qt.Equals, Position{Offset: 14, Line: 1, Column: 15})
+		c.Assert(err, qt.ErrorMatches, "array index can't be less than zero at 1:15 \\(line:column\\)")
+	})
+
+	t.Run("unreachable negative index", func(t *testing.T) {
+		// the '<' is after a loop that never ends
+		s := `+[]<`
+		input := strings.NewReader(s)
+
+		_, err := New(input, io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+	})
+
 	t.Run("invalid loops", func(t *testing.T) {
 		s := `++>+++++[[->+<]`
 		input := strings.NewReader(s)
@@ -161,6 +200,52 @@ func TestBF_RemoveCommand(t *testing.T) {
 	})
 }
 
+func TestBF_RemoveCommand_Instances(t *testing.T) {
+	c := qt.New(t)
+
+	a, err := New(strings.NewReader(`+>+`), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+	a.RemoveCommand('>')
+
+	// the command is only removed from a
+	b, err := New(strings.NewReader(`+>+`), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+	err = b.Exec()
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.Tape(), qt.DeepEquals, []int32{1, 1})
+
+	err = a.Exec()
+	c.Assert(err, qt.IsNil)
+	c.Assert(a.Cell(0), qt.Equals, int32(2))
+}
+
+func TestBF_Exec_Observed(t *testing.T) {
+	c := qt.New(t)
+
+	// Exec has a faster loop for a BF that nothing observes, which must run programs the
+	// same way as the loop of Step
+	for _, s := range []string{
+		"++++++++[>++++[>++>+++<<-]>+>-[<]<-]>>.>.",
+		"+[-]>,[>+<-]>.#<<<",
+		"++\x00+",
+	} {
+		var outA, outB bytes.Buffer
+		a, err := New(strings.NewReader(s), &outA, strings.NewReader("3\n"))
+		c.Assert(err, qt.IsNil)
+		b, err := New(strings.NewReader(s), &outB, strings.NewReader("3\n"), WithHistory(0))
+		c.Assert(err, qt.IsNil)
+		c.Assert(a.observed(), qt.IsFalse)
+		c.Assert(b.observed(), qt.IsTrue)
+
+		errA, errB := a.Exec(), b.Exec()
+		c.Assert(errA, qt.Equals, errB, qt.Commentf(s))
+		c.Assert(outA.String(), qt.Equals, outB.String())
+		c.Assert(a.Tape(), qt.DeepEquals, b.Tape())
+		c.Assert(a.Pos(), qt.Equals, b.Pos())
+		c.Assert(a.Stats().Ops, qt.DeepEquals, b.Stats().Ops)
+	}
+}
+
 func Example() {
 	s := `,+++++[>++++[>++>+++>+++>+<<
 <<-]>+>+>->>+[<]<-]>>.>---.+++++++..+++.>
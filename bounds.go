@@ -0,0 +1,68 @@
+package bf
+
+import (
+	"bytes"
+
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// bounds finds the lowest and the highest cells the data pointer can be on, which is known
+// when every loop has a zero net pointer movement. Then the tape is allocated up front, and
+// '>' and '<' do not check the pointer unless it can go left of the first cell.
+//
+// It returns the offset of the first '<' that moves the pointer left of the first cell
+// on every run, or -1. Such a '<' is before any loop and any NUL character, since a loop
+// may never end and a NUL stops the run. Other moves left of the first cell are reported
+// when they run.
+func (b *BF) bounds(f *syntax.File) int {
+	lo, hi := 0, 0
+	exact := true
+	neg := -1
+	reached := true // the commands run on every run, before the first loop
+	nul := bytes.IndexByte(b.src, 0)
+
+	var walk func(nodes []syntax.Node, ptr int) int
+	walk = func(nodes []syntax.Node, ptr int) int {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *syntax.Command:
+				if !b.isCommand(rune(n.Op)) {
+					continue
+				}
+				switch n.Op {
+				case '>':
+					ptr++
+				case '<':
+					ptr--
+				default:
+					continue
+				}
+				if ptr > hi {
+					hi = ptr
+				}
+				if ptr < lo {
+					lo = ptr
+				}
+				if ptr < 0 && reached && neg < 0 && (nul < 0 || n.Offset < nul) {
+					neg = n.Offset
+				}
+			case *syntax.Loop:
+				reached = false
+				if d, ok := n.Net(); !ok || d != 0 {
+					exact = false
+					return ptr
+				}
+				walk(n.Body, ptr)
+			}
+		}
+		return ptr
+	}
+	walk(f.Nodes, 0)
+	if neg >= 0 || !exact {
+		return neg
+	}
+
+	b.arr = make([]int32, hi+1)
+	b.bounded = lo >= 0
+	return -1
+}
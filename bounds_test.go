@@ -0,0 +1,58 @@
+package bf
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBounds(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name    string
+		src     string
+		cells   int
+		bounded bool
+		err     string
+	}{
+		{"balanced", "+[>>+<<-]>", 3, true, ""},
+		{"no moves", "+.", 1, true, ""},
+		{"nested", "+[>[>>+<<-]<-]", 4, true, ""},
+		{"left of the first cell in a loop", "[<+>-]>", 2, false, ""},
+		{"unbalanced", ",[>,]", size, false, ""},
+		{"after an unbalanced loop", "+[>]<<", size, false, ""},
+		{"left of the first cell", "+>\n<<", 0, false, "array index can't be less than zero at 2:2 \\(line:column\\)"},
+		{"after a loop that never ends", "+[]<", 1, false, ""},
+		{"after a loop", "+[-]<", 1, false, ""},
+		{"after a NUL character", "+\x00<", 1, false, ""},
+	}
+
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			bfi, err := New(strings.NewReader(test.src), io.Discard, strings.NewReader("1\n0\n"))
+			if test.err != "" {
+				c.Assert(err, qt.ErrorIs, ErrNegativeIndex)
+				c.Assert(err, qt.ErrorMatches, test.err)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(bfi.Tape(), qt.HasLen, test.cells)
+			c.Assert(bfi.bounded, qt.Equals, test.bounded)
+		})
+	}
+}
+
+func TestBounds_Exec(t *testing.T) {
+	c := qt.New(t)
+
+	bfi, err := New(strings.NewReader("+++[>++[>+<-]<-]>>"), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(bfi.bounded, qt.IsTrue)
+	err = bfi.Exec()
+	c.Assert(err, qt.IsNil)
+	c.Assert(bfi.Tape(), qt.DeepEquals, []int32{0, 0, 6})
+	c.Assert(bfi.Pointer(), qt.Equals, 2)
+}
@@ -0,0 +1,211 @@
+package bf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/thesoulless/bf/ast"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// ErrCoverageMismatch is returned when coverage of a source is merged with, or reported
+// against, another source.
+var ErrCoverageMismatch = errors.New("coverage is of another source")
+
+// Coverage records which commands ran and which loops were entered and skipped, over one
+// or more runs of the same source. Register it with WithCoverage.
+type Coverage struct {
+	Source   string        `json:"source"`   // hash of the source
+	Commands map[int]int64 `json:"commands"` // runs of each command that ran, keyed by offset
+	Loops    map[int]int64 `json:"loops"`    // entries of each loop entered, keyed by the offset of '['
+
+	NopHooks `json:"-"`
+}
+
+// WithCoverage records the coverage of the run into c. Coverage of previous runs of the same
+// source is kept, so it adds up over runs; c is reset if it holds coverage of another source.
+func WithCoverage(c *Coverage) Option {
+	return func(b *BF) {
+		if h := sourceHash(b.src); c.Source != h || c.Commands == nil || c.Loops == nil {
+			*c = Coverage{Source: h, Commands: make(map[int]int64), Loops: make(map[int]int64)}
+		}
+		WithHooks(c)(b)
+	}
+}
+
+// sourceHash identifies a source in coverage files.
+func sourceHash(src []byte) string {
+	h := sha256.Sum256(src)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// BeforeInstruction implements Hooks.
+func (c *Coverage) BeforeInstruction(pos Position, _ rune) {
+	c.Commands[pos.Offset]++
+}
+
+// OnLoopEnter implements Hooks.
+func (c *Coverage) OnLoopEnter(pos Position) {
+	c.Loops[pos.Offset]++
+}
+
+// Merge adds the coverage of o to c. Both must be of the same source.
+func (c *Coverage) Merge(o *Coverage) error {
+	if c.Source != o.Source {
+		return ErrCoverageMismatch
+	}
+
+	for off, n := range o.Commands {
+		c.Commands[off] += n
+	}
+	for off, n := range o.Loops {
+		c.Loops[off] += n
+	}
+	return nil
+}
+
+// Write writes the coverage to w in JSON, to be read back by ReadCoverage.
+func (c *Coverage) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(c)
+}
+
+// ReadCoverage reads coverage written by Coverage.Write.
+func ReadCoverage(r io.Reader) (*Coverage, error) {
+	var c Coverage
+	err := json.NewDecoder(r).Decode(&c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid coverage: %w", err)
+	}
+	if c.Source == "" {
+		return nil, errors.New("invalid coverage: no source hash")
+	}
+	if c.Commands == nil {
+		c.Commands = make(map[int]int64)
+	}
+	if c.Loops == nil {
+		c.Loops = make(map[int]int64)
+	}
+
+	return &c, nil
+}
+
+// CoverageSummary is the coverage of a source.
+type CoverageSummary struct {
+	Commands, CommandsRun int
+	// Branches counts two branches per loop, entering it and skipping it.
+	Branches, BranchesRun int
+	// Uncovered lists the runs of commands that never ran, ignoring the comments
+	// between them, as the positions of their first and last commands.
+	Uncovered [][2]Position
+	// NeverEntered and NeverSkipped list the positions of the loops that were never
+	// entered or never skipped.
+	NeverEntered, NeverSkipped []Position
+}
+
+// Summary returns the coverage of src, which must be the source the coverage was
+// recorded for.
+func (c *Coverage) Summary(src []byte) (*CoverageSummary, error) {
+	if c.Source != sourceHash(src) {
+		return nil, ErrCoverageMismatch
+	}
+
+	f := parseValid(src)
+	pos := func(off int) Position {
+		line, col := f.Position(off)
+		return Position{Offset: off, Line: line, Column: col}
+	}
+
+	s := &CoverageSummary{}
+	var gap *[2]Position // current run of commands that never ran
+	for off, ch := range src {
+		if !ast.IsCommand(ch) {
+			continue
+		}
+
+		s.Commands++
+		if c.Commands[off] > 0 {
+			s.CommandsRun++
+			gap = nil
+			continue
+		}
+		if gap == nil {
+			s.Uncovered = append(s.Uncovered, [2]Position{pos(off), pos(off)})
+			gap = &s.Uncovered[len(s.Uncovered)-1]
+		}
+		gap[1] = pos(off)
+	}
+
+	f.Inspect(func(n syntax.Node, _ []*syntax.Loop) bool {
+		l, ok := n.(*syntax.Loop)
+		if !ok {
+			return true
+		}
+
+		// each run of '[' either enters the loop or skips it
+		s.Branches += 2
+		runs, entries := c.Commands[l.Open], c.Loops[l.Open]
+		if entries > 0 {
+			s.BranchesRun++
+		} else {
+			s.NeverEntered = append(s.NeverEntered, pos(l.Open))
+		}
+		if runs > entries {
+			s.BranchesRun++
+		} else {
+			s.NeverSkipped = append(s.NeverSkipped, pos(l.Open))
+		}
+		return true
+	})
+
+	return s, nil
+}
+
+// Report writes the coverage percentages of src and its uncovered regions to w.
+func (c *Coverage) Report(w io.Writer, src []byte) error {
+	s, err := c.Summary(src)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "commands\t%d/%d\t%s\n", s.CommandsRun, s.Commands, percent(s.CommandsRun, s.Commands))
+	fmt.Fprintf(tw, "branches\t%d/%d\t%s\n", s.BranchesRun, s.Branches, percent(s.BranchesRun, s.Branches))
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if len(s.Uncovered) > 0 {
+		fmt.Fprintln(w, "commands never run:")
+		for _, r := range s.Uncovered {
+			if r[0] == r[1] {
+				fmt.Fprintf(w, "  %s\n", r[0])
+			} else {
+				fmt.Fprintf(w, "  %s-%s\n", r[0], r[1])
+			}
+		}
+	}
+	if len(s.NeverEntered)+len(s.NeverSkipped) > 0 {
+		fmt.Fprintln(w, "branches never taken:")
+		for _, p := range s.NeverEntered {
+			fmt.Fprintf(w, "  loop %s never entered\n", p)
+		}
+		for _, p := range s.NeverSkipped {
+			fmt.Fprintf(w, "  loop %s never skipped\n", p)
+		}
+	}
+
+	return nil
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
@@ -0,0 +1,124 @@
+package bf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const coverageSrc = `,[>+<-]
+>[-]<[
+  never
+  >>+<<[-]
+]`
+
+// cover runs coverageSrc with each of inputs, recording the coverage into c
+func cover(c *qt.C, cov *Coverage, inputs ...string) {
+	for _, in := range inputs {
+		bfi, err := New(strings.NewReader(coverageSrc), io.Discard, strings.NewReader(in), WithCoverage(cov))
+		c.Assert(err, qt.IsNil)
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+	}
+}
+
+func TestWithCoverage(t *testing.T) {
+	c := qt.New(t)
+
+	var cov Coverage
+	cover(c, &cov, "0\n")
+	s, err := cov.Summary([]byte(coverageSrc))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.Commands, qt.Equals, 22)
+	c.Assert(s.CommandsRun, qt.Equals, 6)
+	c.Assert(s.Branches, qt.Equals, 8)
+	c.Assert(s.BranchesRun, qt.Equals, 3)
+	c.Assert(s.Uncovered, qt.DeepEquals, [][2]Position{
+		{{Offset: 2, Line: 1, Column: 3}, {Offset: 6, Line: 1, Column: 7}},
+		{{Offset: 10, Line: 2, Column: 3}, {Offset: 11, Line: 2, Column: 4}},
+		{{Offset: 25, Line: 4, Column: 3}, {Offset: 34, Line: 5, Column: 1}},
+	})
+
+	// coverage adds up over runs
+	cover(c, &cov, "2\n")
+	s, err = cov.Summary([]byte(coverageSrc))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.CommandsRun, qt.Equals, 13)
+	c.Assert(s.BranchesRun, qt.Equals, 5)
+	c.Assert(s.NeverEntered, qt.DeepEquals, []Position{{Offset: 13, Line: 2, Column: 6}, {Offset: 30, Line: 4, Column: 8}})
+	c.Assert(s.NeverSkipped, qt.DeepEquals, []Position{{Offset: 30, Line: 4, Column: 8}})
+	c.Assert(cov.Commands[1], qt.Equals, int64(2))
+	c.Assert(cov.Loops[1], qt.Equals, int64(1))
+
+	var buf bytes.Buffer
+	err = cov.Report(&buf, []byte(coverageSrc))
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `commands  13/22  59.1%
+branches  5/8    62.5%
+commands never run:
+  4:3-5:1
+branches never taken:
+  loop 2:6 never entered
+  loop 4:8 never entered
+  loop 4:8 never skipped
+`)
+
+	_, err = cov.Summary([]byte("+"))
+	c.Assert(errors.Is(err, ErrCoverageMismatch), qt.IsTrue)
+}
+
+func TestCoverage_SummaryNul(t *testing.T) {
+	c := qt.New(t)
+
+	// the run stops at the NUL character, which the summary skips like a comment
+	src := "+[-]\x00>+"
+	var cov Coverage
+	bfi, err := New(strings.NewReader(src), io.Discard, nil, WithCoverage(&cov))
+	c.Assert(err, qt.IsNil)
+	err = bfi.Exec()
+	c.Assert(errors.Is(err, ErrIllegalCharNul), qt.IsTrue)
+
+	s, err := cov.Summary([]byte(src))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.Commands, qt.Equals, 6)
+	c.Assert(s.CommandsRun, qt.Equals, 4)
+	c.Assert(s.Uncovered, qt.DeepEquals, [][2]Position{
+		{{Offset: 5, Line: 1, Column: 6}, {Offset: 6, Line: 1, Column: 7}},
+	})
+}
+
+func TestCoverage_Merge(t *testing.T) {
+	c := qt.New(t)
+
+	var a, b Coverage
+	cover(c, &a, "0\n")
+	cover(c, &b, "2\n")
+
+	// through a coverage file
+	var buf bytes.Buffer
+	err := b.Write(&buf)
+	c.Assert(err, qt.IsNil)
+	rb, err := ReadCoverage(&buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rb, qt.DeepEquals, &b)
+
+	err = a.Merge(rb)
+	c.Assert(err, qt.IsNil)
+	s, err := a.Summary([]byte(coverageSrc))
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.CommandsRun, qt.Equals, 13)
+	c.Assert(s.BranchesRun, qt.Equals, 5)
+
+	var other Coverage
+	bfi, err := New(strings.NewReader("+"), io.Discard, nil, WithCoverage(&other))
+	c.Assert(err, qt.IsNil)
+	c.Assert(bfi.Exec(), qt.IsNil)
+	c.Assert(errors.Is(a.Merge(&other), ErrCoverageMismatch), qt.IsTrue)
+
+	_, err = ReadCoverage(strings.NewReader(`{"commands":{}}`))
+	c.Assert(err, qt.ErrorMatches, "invalid coverage: no source hash")
+}
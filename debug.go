@@ -0,0 +1,49 @@
+package bf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// debugWindow is the number of cells printed on each side of the current cell by '#'.
+const debugWindow = 4
+
+// WithDebugHash turns '#' into a debugging command that writes the position, the data
+// pointer and the cells around it to w, e.g.
+//
+//	#3:5 ptr=4 cells[0..8]: 0 0 72 101 [108] 0 0 0 0
+//
+// The current cell is put in brackets.
+func WithDebugHash(w io.Writer) Option {
+	return func(b *BF) {
+		b.debug = w
+	}
+}
+
+// dump writes the state of the machine to the debug writer, for the '#' at offset.
+func (b *BF) dump(offset int) error {
+	from := b.c - debugWindow
+	if from < 0 {
+		from = 0
+	}
+	to := b.c + debugWindow
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#%s ptr=%d cells[%d..%d]:", b.position(offset), b.c, from, to)
+	for i := from; i <= to; i++ {
+		if i == b.c {
+			fmt.Fprintf(&sb, " [%d]", b.Cell(i))
+			continue
+		}
+		fmt.Fprintf(&sb, " %d", b.Cell(i))
+	}
+	sb.WriteByte('\n')
+
+	_, err := io.WriteString(b.debug, sb.String())
+	if err != nil {
+		return fmt.Errorf("failed writing the debug output: %v", err)
+	}
+
+	return nil
+}
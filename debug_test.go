@@ -0,0 +1,51 @@
+package bf
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"unsafe"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWithDebugHash(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("dump", func(t *testing.T) {
+		s := `+++++>>>>>>++#
+>-#`
+		var buf []byte
+		dbg := bytes.NewBuffer(buf)
+
+		bfi, err := New(strings.NewReader(s), io.Discard, nil, WithDebugHash(dbg))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+		c.Assert(dbg.String(), qt.Equals, `#1:14 ptr=6 cells[2..10]: 0 0 0 0 [2] 0 0 0 0
+#2:3 ptr=7 cells[3..11]: 0 0 0 2 [-1] 0 0 0 0
+`)
+	})
+
+	t.Run("ignored without the option", func(t *testing.T) {
+		s := `+#+`
+		bfi, err := New(strings.NewReader(s), io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+
+		p, err := bfi.Step()
+		c.Assert(err, qt.IsNil)
+		p, err = bfi.Step()
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 2)
+	})
+
+	t.Run("duplicate command", func(t *testing.T) {
+		bfi, err := New(strings.NewReader(`+`), io.Discard, nil, WithDebugHash(io.Discard))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.AddCommand('#', func(ptr unsafe.Pointer) {})
+		c.Assert(err, qt.Equals, ErrDuplicateCmd)
+	})
+}
@@ -0,0 +1,243 @@
+// Package equiv checks whether two BF programs behave the same, by running both on every
+// input up to a bound: every sequence of input values up to a length, each value in a
+// range, with a budget of steps for each run. Two runs behave the same when they write
+// the same output and both end, or both fail with the same error.
+//
+// Runs stuck in a loop that repeats a state fail with bf.ErrInfiniteLoop, so two programs
+// that never end compare the same. Other runs that reach the step budget are counted as
+// incomplete, unless their outputs already differ.
+//
+// A program that reads fewer values than the bound is not given longer inputs, and reads
+// past the end of an input leave the current cell unchanged, as they do in bf.
+package equiv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/thesoulless/bf"
+)
+
+// DefaultSteps is the step budget of each run when Config.Steps is zero.
+const DefaultSteps = 100000
+
+// Config is the bound of the check.
+type Config struct {
+	Inputs   int   // most input values given to a run
+	Min, Max int32 // range of each input value
+	Steps    int64 // most commands run by a program on an input, DefaultSteps if zero
+}
+
+// Outcome is how a run on an input ended.
+type Outcome struct {
+	Output string
+	Err    error // nil when the program ended without an error
+	Done   bool  // false when the step budget ran out
+}
+
+// String describes the outcome in a line.
+func (o Outcome) String() string {
+	switch {
+	case !o.Done:
+		return fmt.Sprintf("output %q, then ran out of steps", o.Output)
+	case o.Err != nil:
+		return fmt.Sprintf("output %q, then failed: %v", o.Output, o.Err)
+	}
+	return fmt.Sprintf("output %q", o.Output)
+}
+
+// Counterexample is an input on which the two programs behave differently.
+type Counterexample struct {
+	Input []int32
+	A, B  Outcome
+}
+
+// Result is the result of a check.
+type Result struct {
+	Cases int // inputs tried
+	// Incomplete is the number of inputs on which a program ran out of steps before the
+	// runs could be told apart. The programs may still differ on them.
+	Incomplete int
+	// Diff is the first input found on which the programs differ, nil if there is none.
+	// Shorter inputs are tried first.
+	Diff *Counterexample
+}
+
+// Equivalent reports whether the programs behave the same on every input tried.
+func (r *Result) Equivalent() bool {
+	return r.Diff == nil
+}
+
+// checker runs the two programs on the inputs, one length at a time.
+type checker struct {
+	a, b []byte
+	c    Config
+	res  Result
+}
+
+// Check runs the programs a and b on every input within the bound of c, and returns
+// the first input on which they differ, if any. It returns an error if a program does
+// not validate.
+func Check(a, b []byte, c Config) (*Result, error) {
+	if c.Inputs < 0 {
+		return nil, fmt.Errorf("invalid number of inputs %d", c.Inputs)
+	}
+	if c.Min > c.Max {
+		return nil, fmt.Errorf("invalid input range [%d, %d]", c.Min, c.Max)
+	}
+	if c.Steps == 0 {
+		c.Steps = DefaultSteps
+	}
+	_, err := bf.New(bytes.NewReader(a), io.Discard, strings.NewReader(""))
+	if err != nil {
+		return nil, fmt.Errorf("program a: %w", err)
+	}
+	_, err = bf.New(bytes.NewReader(b), io.Discard, strings.NewReader(""))
+	if err != nil {
+		return nil, fmt.Errorf("program b: %w", err)
+	}
+
+	x := &checker{a: a, b: b, c: c}
+	for n := 0; n <= c.Inputs; n++ {
+		more, err := x.explore(nil, n)
+		if err != nil {
+			return nil, err
+		}
+		if x.res.Diff != nil || !more {
+			break
+		}
+	}
+	return &x.res, nil
+}
+
+// explore tries the inputs of length n starting with input, where the programs read
+// them. It reports whether a program reads more than n values on one of them.
+func (x *checker) explore(input []int32, n int) (bool, error) {
+	oa, ra, err := run(x.a, input, x.c.Steps)
+	if err != nil {
+		return false, err
+	}
+	ob, rb, err := run(x.b, input, x.c.Steps)
+	if err != nil {
+		return false, err
+	}
+	reads := ra > int64(len(input)) || rb > int64(len(input))
+
+	if len(input) == n {
+		x.res.Cases++
+		switch compare(oa, ob) {
+		case differ:
+			x.res.Diff = &Counterexample{Input: input, A: oa, B: ob}
+		case unknown:
+			x.res.Incomplete++
+		}
+		return reads, nil
+	}
+	if !reads {
+		// neither program reads this far, so this input was tried with a shorter length
+		return false, nil
+	}
+
+	more := false
+	for v := int64(x.c.Min); v <= int64(x.c.Max); v++ {
+		next := append(input[:len(input):len(input)], int32(v))
+		m, err := x.explore(next, n)
+		if err != nil {
+			return false, err
+		}
+		if x.res.Diff != nil {
+			return false, nil
+		}
+		more = more || m
+	}
+	return more, nil
+}
+
+// run runs src on input for at most steps commands, and returns how it ended, and the
+// number of values it tried to read. An error is returned only when the output can not
+// be written.
+func run(src []byte, input []int32, steps int64) (Outcome, int64, error) {
+	var in strings.Builder
+	for _, v := range input {
+		in.WriteString(strconv.Itoa(int(v)))
+		in.WriteByte('\n')
+	}
+
+	var out strings.Builder
+	b, err := bf.New(bytes.NewReader(src), &out, strings.NewReader(in.String()),
+		bf.WithLoopDetection())
+	if err != nil {
+		return Outcome{}, 0, err
+	}
+
+	var o Outcome
+	for i := int64(0); i < steps; i++ {
+		_, err = b.Step()
+		if err == io.EOF {
+			o.Done = true
+			break
+		}
+		if err != nil {
+			o.Done = true
+			o.Err = err
+			break
+		}
+	}
+	o.Output = out.String()
+	return o, b.Stats().Ops[','], nil
+}
+
+// verdict is the result of comparing two outcomes.
+type verdict int
+
+const (
+	same verdict = iota
+	differ
+	unknown // a run ran out of steps before the outcomes differed
+)
+
+func compare(a, b Outcome) verdict {
+	switch {
+	case a.Done && b.Done:
+		if a.Output != b.Output || !sameErr(a.Err, b.Err) {
+			return differ
+		}
+		return same
+	case a.Done:
+		if !strings.HasPrefix(a.Output, b.Output) {
+			return differ
+		}
+	case b.Done:
+		if !strings.HasPrefix(b.Output, a.Output) {
+			return differ
+		}
+	default:
+		if !strings.HasPrefix(a.Output, b.Output) && !strings.HasPrefix(b.Output, a.Output) {
+			return differ
+		}
+	}
+	return unknown
+}
+
+// sameErr reports whether a and b are the same error, ignoring the positions they are
+// wrapped with.
+func sameErr(a, b error) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return cause(a).Error() == cause(b).Error()
+}
+
+func cause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
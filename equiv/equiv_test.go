@@ -0,0 +1,136 @@
+package equiv
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         string
+		c            Config
+		cases        int
+		incomplete   int
+		input        []int32
+		outA, outB   string
+		errA, errB   error
+		doneA, doneB bool
+	}{{
+		name:  "no input",
+		a:     "+++[->++<]>.",
+		b:     "comment >++++++.",
+		cases: 1,
+	}, {
+		name:  "same",
+		a:     ",[->+<]>.",
+		b:     ",>[-]<[->+<]>.",
+		c:     Config{Inputs: 2, Max: 255},
+		cases: 257,
+	}, {
+		name:  "second value",
+		a:     ",>,.",
+		b:     ",>,[-].",
+		c:     Config{Inputs: 2, Max: 1},
+		input: []int32{0, 1},
+		outA:  "\x01",
+		outB:  "\x00",
+		doneA: true, doneB: true,
+	}, {
+		name:  "differ",
+		a:     ",[->+<]>.",
+		b:     ",[->+<]>[-]+.",
+		c:     Config{Inputs: 1, Min: 1, Max: 3},
+		input: nil,
+		outA:  "\x00",
+		outB:  "\x01",
+		doneA: true, doneB: true,
+	}, {
+		name:  "differ on a value",
+		a:     ",.",
+		b:     ",--[++.>]<",
+		c:     Config{Inputs: 1, Min: 1, Max: 3},
+		input: []int32{2},
+		outA:  "\x02",
+		outB:  "",
+		errB:  bf.ErrNegativeIndex,
+		doneA: true, doneB: true,
+	}, {
+		name:  "same error",
+		a:     ",[<]",
+		b:     ",[-<]",
+		c:     Config{Inputs: 1, Max: 2},
+		cases: 4,
+	}, {
+		name:  "infinite loops",
+		a:     "+[]",
+		b:     "+[-+]",
+		cases: 1,
+	}, {
+		name:       "out of steps",
+		a:          "+[>+]",
+		b:          "+[>+]",
+		c:          Config{Steps: 100},
+		cases:      1,
+		incomplete: 1,
+	}, {
+		name:  "output before the budget",
+		a:     "+.[>+]",
+		b:     "++.[>+]",
+		c:     Config{Steps: 100},
+		outA:  "\x01",
+		outB:  "\x02",
+		input: nil,
+	}}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			r, err := Check([]byte(test.a), []byte(test.b), test.c)
+			c.Assert(err, qt.IsNil)
+			if test.outA == "" && test.outB == "" && test.errB == nil {
+				c.Assert(r.Equivalent(), qt.IsTrue, qt.Commentf("%+v", r.Diff))
+				c.Assert(r.Cases, qt.Equals, test.cases)
+				c.Assert(r.Incomplete, qt.Equals, test.incomplete)
+				return
+			}
+
+			c.Assert(r.Equivalent(), qt.IsFalse)
+			d := r.Diff
+			c.Assert(d.Input, qt.DeepEquals, test.input)
+			c.Assert(d.A.Output, qt.Equals, test.outA)
+			c.Assert(d.B.Output, qt.Equals, test.outB)
+			c.Assert(d.A.Done, qt.Equals, test.doneA)
+			c.Assert(d.B.Done, qt.Equals, test.doneB)
+			if test.errB != nil {
+				c.Assert(d.B.Err, qt.ErrorIs, test.errB)
+			}
+		})
+	}
+}
+
+func TestCheck_Invalid(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := Check([]byte("+"), []byte("+]"), Config{})
+	c.Assert(err, qt.ErrorMatches, "program b: .*")
+	c.Assert(errors.Is(err, bf.ErrLoopDoesNotMatch), qt.IsTrue)
+
+	_, err = Check([]byte("+"), []byte("+"), Config{Min: 1})
+	c.Assert(err, qt.ErrorMatches, `invalid input range \[1, 0\]`)
+}
+
+func TestOutcome_String(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(Outcome{Output: "a", Done: true}.String(), qt.Equals, `output "a"`)
+	c.Assert(Outcome{Output: "a"}.String(), qt.Equals, `output "a", then ran out of steps`)
+	o := Outcome{Err: fmt.Errorf("%w at 1:2", bf.ErrNegativeIndex), Done: true}
+	c.Assert(o.String(), qt.Equals, `output "", then failed: array index can't be less than zero at 1:2`)
+}
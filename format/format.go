@@ -0,0 +1,211 @@
+// Package format formats BF sources in a canonical style. Loops that hold other loops or
+// comments get their brackets on their own lines, with their bodies indented one level
+// deeper, and the other loops are written inline, like [->+<]. Runs of commands are
+// packed on lines up to a width, breaking lines between runs. Comments stay next to the
+// commands they follow, at the end of their line, or on their own lines when they were,
+// and blank lines between commands and comments are kept, one at most.
+//
+// Formatting does not change what a program does, and formatting formatted source
+// again gives it back unchanged.
+package format
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/thesoulless/bf/ast"
+)
+
+const (
+	// DefaultWidth is the width of a line when Config.Width is zero.
+	DefaultWidth = 60
+	// DefaultIndent is the indentation of a level when Config.Indent is empty.
+	DefaultIndent = "    "
+)
+
+// Config is the style of the formatted source.
+type Config struct {
+	// Width is the number of commands on a line, not counting the indentation and the
+	// comments. Longer runs are split. It is DefaultWidth if zero.
+	Width int
+	// Indent is the indentation of each loop level. It is DefaultIndent if empty.
+	Indent string
+}
+
+// item is a command, a comment, a line end, or a loop.
+type item struct {
+	op      byte   // command, 0 for the other items
+	comment string // comment text, without the surrounding whitespace
+	newline bool
+	loop    []item // body of a loop, nil for the other items
+	isLoop  bool
+}
+
+// Source formats src in the style of c. It returns an error if the loops of src do not
+// match, or it holds a NUL character.
+func Source(src []byte, c Config) ([]byte, error) {
+	f, err := ast.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if c.Width <= 0 {
+		c.Width = DefaultWidth
+	}
+	if c.Indent == "" {
+		c.Indent = DefaultIndent
+	}
+
+	p := &printer{c: c}
+	p.list(items(f.Nodes), 0)
+	p.flush()
+	return p.out.Bytes(), nil
+}
+
+// items returns the items of nodes, which hold no Bad node. Each line of a comment is an
+// item of its own, without the surrounding whitespace.
+func items(nodes []ast.Node) []item {
+	var res []item
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.Command:
+			res = append(res, item{op: n.Op})
+		case *ast.Loop:
+			res = append(res, item{loop: items(n.Body), isLoop: true})
+		case *ast.Comment:
+			for i, line := range strings.Split(n.Text, "\n") {
+				if i > 0 {
+					res = append(res, item{newline: true})
+				}
+				if text := strings.TrimSpace(line); text != "" {
+					res = append(res, item{comment: text})
+				}
+			}
+		}
+	}
+	return res
+}
+
+// printer writes the formatted items, a line at a time.
+type printer struct {
+	c   Config
+	out bytes.Buffer
+
+	line   []byte // current line, without the indentation
+	depth  int    // indentation of the current line
+	closed bool   // the line is a bracket, only a comment can be added to it
+	nl     int    // line ends since the last item
+	blank  bool   // a blank line goes before the next line
+}
+
+// list writes items at the indentation depth.
+func (p *printer) list(items []item, depth int) {
+	for i := 0; i < len(items); i++ {
+		it := items[i]
+		if it.newline {
+			p.nl++
+			continue
+		}
+		if p.nl >= 2 {
+			p.flush()
+			p.blank = true
+		}
+
+		switch {
+		case it.comment != "":
+			if p.nl == 0 && len(p.line) > 0 {
+				p.line = append(p.line, ' ')
+				p.line = append(p.line, it.comment...)
+				p.flush()
+			} else {
+				p.flush()
+				p.writeLine(depth, []byte(it.comment))
+			}
+		case it.isLoop:
+			if text, ok := p.inline(it.loop); ok {
+				p.add(depth, text)
+				break
+			}
+			p.flush()
+			p.start(depth, "[")
+			p.nl = 0
+			p.list(it.loop, depth+1)
+			p.flush()
+			p.start(depth, "]")
+		default:
+			// a run of the command, across single line ends
+			run := []byte{it.op}
+			for j := i + 1; j < len(items); j++ {
+				if items[j].op == it.op {
+					run = append(run, it.op)
+					i = j
+				} else if !items[j].newline || (j+1 < len(items) && items[j+1].newline) {
+					break
+				}
+			}
+			p.add(depth, string(run))
+		}
+		p.nl = 0
+	}
+}
+
+// inline returns the text of a loop written on one line, if it only holds commands other
+// than loops and fits in the width.
+func (p *printer) inline(body []item) (string, bool) {
+	text := []byte{'['}
+	for _, it := range body {
+		switch {
+		case it.newline:
+		case it.op != 0:
+			text = append(text, it.op)
+		default:
+			return "", false
+		}
+	}
+	text = append(text, ']')
+	return string(text), len(text) <= p.c.Width
+}
+
+// add adds a run of commands or an inline loop to the line, starting a new line if it
+// does not fit. Runs longer than the width are split.
+func (p *printer) add(depth int, text string) {
+	if p.closed || (len(p.line) > 0 && len(p.line)+len(text) > p.c.Width) {
+		p.flush()
+	}
+	for len(text) > p.c.Width {
+		p.writeLine(depth, []byte(text[:p.c.Width]))
+		text = text[p.c.Width:]
+	}
+	if len(p.line) == 0 {
+		p.depth = depth
+	}
+	p.line = append(p.line, text...)
+}
+
+// start starts a line holding a bracket.
+func (p *printer) start(depth int, bracket string) {
+	p.depth = depth
+	p.line = append(p.line, bracket...)
+	p.closed = true
+}
+
+// flush writes the current line, if any.
+func (p *printer) flush() {
+	if len(p.line) == 0 {
+		return
+	}
+	p.writeLine(p.depth, p.line)
+	p.line = p.line[:0]
+	p.closed = false
+}
+
+func (p *printer) writeLine(depth int, text []byte) {
+	if p.blank && p.out.Len() > 0 {
+		p.out.WriteByte('\n')
+	}
+	p.blank = false
+	for i := 0; i < depth; i++ {
+		p.out.WriteString(p.c.Indent)
+	}
+	p.out.Write(text)
+	p.out.WriteByte('\n')
+}
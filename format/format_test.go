@@ -0,0 +1,80 @@
+package format
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf/ast"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		c    Config
+		want string
+	}{{
+		name: "empty",
+		src:  "  \n\n",
+		want: "",
+	}, {
+		name: "runs",
+		src:  "+++\n>>>---\n<<<.",
+		c:    Config{Width: 7},
+		want: "+++>>>\n---<<<.\n",
+	}, {
+		name: "long run",
+		src:  ">++++++++++",
+		c:    Config{Width: 4},
+		want: ">\n++++\n++++\n++\n",
+	}, {
+		name: "inline loops",
+		src:  "+[-]\n>[\n->+<\n]",
+		want: "+[-]>[->+<]\n",
+	}, {
+		name: "loop longer than the width",
+		src:  "[->+<]",
+		c:    Config{Width: 5},
+		want: "[\n    ->+<\n]\n",
+	}, {
+		name: "nested loops",
+		src:  "++[>++[>+<-]<-]",
+		c:    Config{Indent: "\t"},
+		want: "++\n[\n\t>++[>+<-]<-\n]\n",
+	}, {
+		name: "comments",
+		src:  "  first line  \n+++ add three >> move\n[ loop\n  -\n  inside\n] done",
+		want: "first line\n+++ add three\n>> move\n[ loop\n    -\n    inside\n] done\n",
+	}, {
+		name: "comment before commands",
+		src:  "add +++",
+		want: "add\n+++\n",
+	}, {
+		name: "blank lines",
+		src:  "\n\n+\n\n\n\n-\n\nend\n\n",
+		want: "+\n\n-\n\nend\n",
+	}}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			got, err := Source([]byte(test.src), test.c)
+			c.Assert(err, qt.IsNil)
+			c.Assert(string(got), qt.Equals, test.want)
+
+			again, err := Source(got, test.c)
+			c.Assert(err, qt.IsNil)
+			c.Assert(string(again), qt.Equals, test.want)
+		})
+	}
+}
+
+func TestSource_Error(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := Source([]byte("+[>"), Config{})
+	c.Assert(errors.Is(err, ast.ErrLoopDoesNotMatch), qt.IsTrue)
+}
@@ -0,0 +1,129 @@
+package bf
+
+import "errors"
+
+// ErrNoHistory is returned when stepping back past the first recorded command.
+var ErrNoHistory = errors.New("no history to step back")
+
+// undo holds what a command changed, so it can be reverted.
+type undo struct {
+	pc    int   // offset of the command
+	ptr   int   // data pointer before the command
+	cell  int32 // value of the current cell before the command
+	depth int   // loop stack depth before the command
+	top   int   // innermost loop before the command, if depth > 0
+	input *string
+}
+
+// WithHistory records an undo log of the commands run, so StepBack and ReverseContinue
+// can revert them. With a limit, the oldest commands are dropped in bulk: the last limit
+// commands are always kept, and at most 2*limit-1 are. All of them are kept if limit is
+// zero.
+//
+// Inputs consumed by reverted commands are read again when the commands are run again,
+// and outputs are not written twice. Changes made by custom commands to cells other
+// than the current one are not recorded.
+func WithHistory(limit int) Option {
+	return func(b *BF) {
+		b.history = true
+		b.histLimit = limit
+	}
+}
+
+// record adds the undo entry for the command the scanner is on.
+func (b *BF) record() {
+	u := undo{pc: b.pc, ptr: b.c, cell: b.arr[b.c], depth: len(b.lpos)}
+	if u.depth > 0 {
+		u.top = b.lpos[u.depth-1]
+	}
+
+	if b.histLimit > 0 && len(b.log) >= 2*b.histLimit {
+		// drop the oldest entries in bulk, to not copy the log on every command
+		n := copy(b.log, b.log[len(b.log)-b.histLimit:])
+		b.log = b.log[:n]
+	}
+	b.log = append(b.log, u)
+}
+
+// readInput reads the next line of the input, first taking the lines given back
+// by reverted commands.
+func (b *BF) readInput() string {
+	var text string
+	if n := len(b.replay); n > 0 {
+		text = b.replay[n-1]
+		b.replay = b.replay[:n-1]
+	} else if b.inpscan.Scan() {
+		text = b.inpscan.Text()
+		b.inBytes += int64(len(text)) + 1
+	} else {
+		return ""
+	}
+
+	if b.history {
+		b.log[len(b.log)-1].input = &text
+	}
+	return text
+}
+
+// StepBack reverts the last command run, and returns its position, which is the
+// position of the next command to run. It returns ErrNoHistory if there is no
+// command to revert. It requires the WithHistory option. After a failed command, it
+// reverts the command before it, and the program can run again.
+func (b *BF) StepBack() (Position, error) {
+	b.hits = nil
+	if len(b.log) == 0 {
+		return b.Pos(), ErrNoHistory
+	}
+
+	u := b.log[len(b.log)-1]
+	b.log = b.log[:len(b.log)-1]
+
+	if len(b.watches) > 0 {
+		// report the watchpoints the command triggered when it ran
+		b.watch(b.position(u.pc), rune(b.src[u.pc]), u.ptr, b.c, u.cell, b.Cell(u.ptr))
+	}
+
+	b.pc = u.pc
+	b.c = u.ptr
+	b.arr[b.c] = u.cell
+	if len(b.lpos) > u.depth {
+		b.lpos = b.lpos[:u.depth]
+	} else if len(b.lpos) < u.depth {
+		b.lpos = append(b.lpos, u.top)
+	}
+	if u.input != nil {
+		b.replay = append(b.replay, *u.input)
+	}
+	b.err = nil
+	b.redo++
+	b.steps--
+	b.ops[b.src[u.pc]]--
+	if b.cycles != nil {
+		b.cycles.reset(b)
+	}
+
+	return b.position(u.pc), nil
+}
+
+// ReverseContinue reverts commands until the next command to run is on a breakpoint,
+// or it reverts a command that triggered a watchpoint. It returns the position of
+// the next command to run, or ErrNoHistory if it reaches the first recorded command.
+func (b *BF) ReverseContinue() (Position, error) {
+	// always revert the last command, so a stopped program can move backward
+	p, err := b.StepBack()
+	for err == nil {
+		if len(b.hits) > 0 {
+			return p, nil
+		}
+		if _, ok := b.bps[p.Offset]; ok {
+			return p, nil
+		}
+		if b.interrupted() {
+			return p, ErrInterrupted
+		}
+
+		p, err = b.StepBack()
+	}
+
+	return p, err
+}
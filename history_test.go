@@ -0,0 +1,166 @@
+package bf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBF_StepBack(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("revert and replay", func(t *testing.T) {
+		s := `,[>+.<-]`
+		var buf []byte
+		out := bytes.NewBuffer(buf)
+
+		bfi, err := New(strings.NewReader(s), out, strings.NewReader("2\n"), WithHistory(0))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+		c.Assert(out.Bytes(), qt.ContentEquals, []byte{1, 2})
+		tape := bfi.Tape()
+
+		// back to the start, reverting the input and every cell write
+		n := 0
+		for {
+			_, err := bfi.StepBack()
+			if err == ErrNoHistory {
+				break
+			}
+			c.Assert(err, qt.IsNil)
+			n++
+		}
+		c.Assert(n, qt.Equals, 14)
+		c.Assert(bfi.Pos().Offset, qt.Equals, 0)
+		c.Assert(bfi.Pointer(), qt.Equals, 0)
+		c.Assert(bfi.Cell(0), qt.Equals, int32(0))
+		c.Assert(bfi.Cell(1), qt.Equals, int32(0))
+		c.Assert(bfi.LoopStack(), qt.HasLen, 0)
+
+		// running again reads the same input, without writing the outputs twice
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+		c.Assert(bfi.Tape(), qt.DeepEquals, tape)
+		c.Assert(out.Bytes(), qt.ContentEquals, []byte{1, 2})
+	})
+
+	t.Run("loop stack", func(t *testing.T) {
+		s := `+[-]+`
+		bfi, err := New(strings.NewReader(s), &bytes.Buffer{}, nil, WithHistory(0))
+		c.Assert(err, qt.IsNil)
+
+		p, err := bfi.RunUntil(4)
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 4)
+		c.Assert(bfi.LoopStack(), qt.HasLen, 0)
+
+		p, err = bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 3)
+		c.Assert(bfi.LoopStack(), qt.HasLen, 1)
+
+		_, err = bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		_, err = bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		c.Assert(bfi.LoopStack(), qt.HasLen, 0)
+		c.Assert(bfi.Cell(0), qt.Equals, int32(1))
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		s := `++++++++++`
+		bfi, err := New(strings.NewReader(s), &bytes.Buffer{}, nil, WithHistory(3))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+
+		n := 0
+		for ; ; n++ {
+			_, err := bfi.StepBack()
+			if err != nil {
+				c.Assert(err, qt.Equals, ErrNoHistory)
+				break
+			}
+		}
+		// between limit and 2*limit-1 commands are kept
+		c.Assert(n >= 3 && n <= 5, qt.IsTrue, qt.Commentf("%d commands kept", n))
+		c.Assert(bfi.Cell(0), qt.Equals, int32(10-n))
+	})
+
+	t.Run("failed command", func(t *testing.T) {
+		bfi, err := New(strings.NewReader(`+>,`), &bytes.Buffer{}, strings.NewReader("x\n"), WithHistory(0))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.ErrorMatches, `invalid input: .*`)
+		c.Assert(bfi.Stats().Steps, qt.Equals, int64(2))
+
+		// the ',' that failed is not reverted
+		p, err := bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 1)
+		c.Assert(bfi.Pointer(), qt.Equals, 0)
+		c.Assert(bfi.Stats().Steps, qt.Equals, int64(1))
+		c.Assert(bfi.Stats().Ops['>'], qt.Equals, int64(0))
+		c.Assert(bfi.Stats().Ops[','], qt.Equals, int64(0))
+
+		p, err = bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 0)
+		c.Assert(bfi.Cell(0), qt.Equals, int32(0))
+		_, err = bfi.StepBack()
+		c.Assert(err, qt.Equals, ErrNoHistory)
+		c.Assert(bfi.Stats().Steps, qt.Equals, int64(0))
+	})
+
+	t.Run("without history", func(t *testing.T) {
+		bfi, err := New(strings.NewReader(`+`), &bytes.Buffer{}, nil)
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+		_, err = bfi.StepBack()
+		c.Assert(err, qt.Equals, ErrNoHistory)
+	})
+}
+
+func TestBF_ReverseContinue(t *testing.T) {
+	c := qt.New(t)
+
+	s := `+++[>++<-]>[-]`
+	bfi, err := New(strings.NewReader(s), &bytes.Buffer{}, nil, WithHistory(0))
+	c.Assert(err, qt.IsNil)
+
+	err = bfi.Exec()
+	c.Assert(err, qt.IsNil)
+
+	w, err := ParseWatchpoint("cell[1]==4")
+	c.Assert(err, qt.IsNil)
+	bfi.AddWatchpoint(w)
+
+	// the last time cell 1 became 4 was in the clearing loop, going down from 5
+	p, err := bfi.ReverseContinue()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 12)
+	c.Assert(bfi.Cell(1), qt.Equals, int32(5))
+	c.Assert(bfi.WatchHits()[0].String(), qt.Equals, "cell[1]==4 at 1:13: 5 -> 4")
+
+	p, err = bfi.ReverseContinue()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 6)
+	c.Assert(bfi.Cell(1), qt.Equals, int32(3))
+
+	_, err = bfi.SetBreakpoint(0)
+	c.Assert(err, qt.IsNil)
+	p, err = bfi.ReverseContinue()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 0)
+
+	_, err = bfi.ReverseContinue()
+	c.Assert(err, qt.Equals, ErrNoHistory)
+}
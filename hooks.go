@@ -0,0 +1,107 @@
+package bf
+
+// Hooks observes the execution of a BF. Its methods are called from the execution
+// loop, so they should return quickly. Embed NopHooks to implement only some of them.
+type Hooks interface {
+	// BeforeInstruction is called before running the command cmd at pos.
+	BeforeInstruction(pos Position, cmd rune)
+	// OnLoopEnter is called when the loop opened at pos is entered.
+	OnLoopEnter(pos Position)
+	// OnLoopExit is called when the loop opened at pos is left.
+	OnLoopExit(pos Position)
+	// OnInput is called after a value is read into the current cell.
+	OnInput(value int32)
+	// OnOutput is called after the current cell value is written to the output.
+	OnOutput(value int32)
+	// OnPointerMove is called after the data pointer moves.
+	OnPointerMove(from, to int)
+	// OnError is called when running the command at pos fails.
+	OnError(pos Position, err error)
+}
+
+// NopHooks implements Hooks with methods that do nothing.
+type NopHooks struct{}
+
+// BeforeInstruction implements Hooks.
+func (NopHooks) BeforeInstruction(Position, rune) {}
+
+// OnLoopEnter implements Hooks.
+func (NopHooks) OnLoopEnter(Position) {}
+
+// OnLoopExit implements Hooks.
+func (NopHooks) OnLoopExit(Position) {}
+
+// OnInput implements Hooks.
+func (NopHooks) OnInput(int32) {}
+
+// OnOutput implements Hooks.
+func (NopHooks) OnOutput(int32) {}
+
+// OnPointerMove implements Hooks.
+func (NopHooks) OnPointerMove(int, int) {}
+
+// OnError implements Hooks.
+func (NopHooks) OnError(Position, error) {}
+
+// Option configures a BF created by New.
+type Option func(b *BF)
+
+// WithHooks registers h to observe the execution. It can be used more than once,
+// the hooks are called in the order they are registered.
+func WithHooks(h Hooks) Option {
+	return func(b *BF) {
+		switch hs := b.hooks.(type) {
+		case nil:
+			b.hooks = h
+		case multiHooks:
+			b.hooks = append(hs, h)
+		default:
+			b.hooks = multiHooks{hs, h}
+		}
+	}
+}
+
+// multiHooks calls a list of hooks in order.
+type multiHooks []Hooks
+
+func (m multiHooks) BeforeInstruction(pos Position, cmd rune) {
+	for _, h := range m {
+		h.BeforeInstruction(pos, cmd)
+	}
+}
+
+func (m multiHooks) OnLoopEnter(pos Position) {
+	for _, h := range m {
+		h.OnLoopEnter(pos)
+	}
+}
+
+func (m multiHooks) OnLoopExit(pos Position) {
+	for _, h := range m {
+		h.OnLoopExit(pos)
+	}
+}
+
+func (m multiHooks) OnInput(value int32) {
+	for _, h := range m {
+		h.OnInput(value)
+	}
+}
+
+func (m multiHooks) OnOutput(value int32) {
+	for _, h := range m {
+		h.OnOutput(value)
+	}
+}
+
+func (m multiHooks) OnPointerMove(from, to int) {
+	for _, h := range m {
+		h.OnPointerMove(from, to)
+	}
+}
+
+func (m multiHooks) OnError(pos Position, err error) {
+	for _, h := range m {
+		h.OnError(pos, err)
+	}
+}
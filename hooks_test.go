@@ -0,0 +1,79 @@
+package bf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+type recorder struct {
+	NopHooks
+	events []string
+}
+
+func (r *recorder) OnLoopEnter(pos Position) {
+	r.events = append(r.events, fmt.Sprintf("enter %s", pos))
+}
+
+func (r *recorder) OnLoopExit(pos Position) {
+	r.events = append(r.events, fmt.Sprintf("exit %s", pos))
+}
+
+func (r *recorder) OnInput(value int32) {
+	r.events = append(r.events, fmt.Sprintf("input %d", value))
+}
+
+func (r *recorder) OnOutput(value int32) {
+	r.events = append(r.events, fmt.Sprintf("output %d", value))
+}
+
+func (r *recorder) OnPointerMove(from, to int) {
+	r.events = append(r.events, fmt.Sprintf("move %d %d", from, to))
+}
+
+func (r *recorder) OnError(pos Position, err error) {
+	r.events = append(r.events, fmt.Sprintf("error %s %v", pos, err))
+}
+
+type counter struct {
+	NopHooks
+	n int
+}
+
+func (c *counter) BeforeInstruction(Position, rune) {
+	c.n++
+}
+
+func TestWithHooks(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("events", func(t *testing.T) {
+		s := `,[->+<]>.[<<]`
+		var buf []byte
+		out := bytes.NewBuffer(buf)
+
+		r := &recorder{}
+		cnt := &counter{}
+		bfi, err := New(strings.NewReader(s), out, strings.NewReader("1\n"), WithHooks(r), WithHooks(cnt))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.ErrorIs, ErrNegativeIndex)
+		c.Assert(r.events, qt.DeepEquals, []string{
+			"input 1",
+			"enter 1:2",
+			"move 0 1",
+			"move 1 0",
+			"exit 1:2",
+			"move 0 1",
+			"output 1",
+			"enter 1:10",
+			"move 1 0",
+			"error 1:12 " + ErrNegativeIndex.Error(),
+		})
+		c.Assert(cnt.n, qt.Equals, 12)
+	})
+}
@@ -0,0 +1,179 @@
+package bf
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/thesoulless/bf/ast"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// heatColors are the background colors of the commands, from the coldest to the hottest.
+var heatColors = []string{
+	"#ffffcc", "#ffeda0", "#fed976", "#feb24c", "#fd8d3c", "#fc4e2a", "#e31a1c", "#bd0026", "#800026",
+}
+
+// darkHeat is the first heat level drawn with light text.
+const darkHeat = 6
+
+var htmlTemplate = template.Must(template.New("profile").Funcs(template.FuncMap{
+	"dark": func(level int) bool { return level >= darkHeat },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} profile</title>
+<style>
+body { background: #fff; color: #222; font-family: sans-serif; margin: 1em 2em; }
+pre { font-family: Menlo, Consolas, monospace; font-size: 14px; line-height: 1.4; }
+.comment { color: #999; }
+.cold { background: #eee; color: #888; }
+{{- range $i, $c := .Colors}}
+.h{{$i}} { background: {{$c}};{{if dark $i}} color: #fff;{{end}} }
+{{- end}}
+.legend span { display: inline-block; padding: 0 0.6em; font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{.Steps}} steps in {{.Duration}}. Hover a command to see how many times it ran, and the
+iterations of the loop for a bracket.</p>
+<p class="legend">
+<span class="cold">not run</span>
+{{- range .Legend}}<span class="{{.Class}}">{{.Text}}</span>{{end}}
+</p>
+<pre>
+{{- range .Segments}}{{if .Class}}<span class="{{.Class}}"{{if .Title}} title="{{.Title}}"{{end}}>{{.Text}}</span>{{else}}{{.Text}}{{end}}{{end -}}
+</pre>
+</body>
+</html>
+`))
+
+// htmlSegment is a part of the source, with a class and a tooltip.
+type htmlSegment struct {
+	Class string
+	Title string
+	Text  string
+}
+
+// WriteHTML writes a self-contained HTML page to w, showing the source with each command
+// colored by the number of times it ran, on a logarithmic scale. Each command has a tooltip
+// with its count, and the brackets have the stats of their loop. name is shown as the
+// title of the page.
+func (p *Profile) WriteHTML(w io.Writer, name string) error {
+	f := parseValid(p.src)
+	loops := make(map[int]*syntax.Loop)
+	f.Inspect(func(n syntax.Node, _ []*syntax.Loop) bool {
+		if l, ok := n.(*syntax.Loop); ok {
+			loops[l.Open] = l
+			loops[l.Close] = l
+		}
+		return true
+	})
+
+	var max int64
+	for _, n := range p.Counts {
+		if n > max {
+			max = n
+		}
+	}
+
+	var segs []htmlSegment
+	for off := 0; off < len(p.src); {
+		if !ast.IsCommand(p.src[off]) {
+			end := off + 1
+			for end < len(p.src) && !ast.IsCommand(p.src[end]) {
+				end++
+			}
+			segs = append(segs, htmlSegment{Class: "comment", Text: string(p.src[off:end])})
+			off = end
+			continue
+		}
+
+		n := p.Counts[off]
+		title := runs(n)
+		if l, ok := loops[off]; ok {
+			title += "\n" + p.loopTitle(f, l)
+		}
+		seg := htmlSegment{Class: heatClass(n, max), Title: title, Text: string(p.src[off])}
+		if last := len(segs) - 1; last >= 0 && segs[last].Class == seg.Class && segs[last].Title == seg.Title {
+			// a run of commands with the same count
+			segs[last].Text += seg.Text
+		} else {
+			segs = append(segs, seg)
+		}
+		off++
+	}
+
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Name":     name,
+		"Steps":    p.Steps,
+		"Duration": p.Duration,
+		"Colors":   heatColors,
+		"Legend":   heatLegend(max),
+		"Segments": segs,
+	})
+}
+
+// loopTitle describes the stats of the loop l.
+func (p *Profile) loopTitle(f *syntax.File, l *syntax.Loop) string {
+	line, col := f.Position(l.Open)
+	s, ok := p.Loops[l.Open]
+	if !ok {
+		return fmt.Sprintf("loop %d:%d: never entered", line, col)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "loop %d:%d: %d entries, %d iterations, %.1f per entry, %d steps, %v",
+		line, col, s.Entries, s.Iterations, float64(s.Iterations)/float64(s.Entries), s.Steps, s.Time.Round(time.Microsecond))
+	fmt.Fprintf(&b, "\niterations per entry: %s", histogram(s.Histogram))
+	return b.String()
+}
+
+func runs(n int64) string {
+	if n == 1 {
+		return "1 run"
+	}
+	return fmt.Sprintf("%d runs", n)
+}
+
+// heatLevel returns the heat level of a count, on a logarithmic scale up to max.
+func heatLevel(n, max int64) int {
+	if max <= 1 {
+		return 0
+	}
+	l := int(math.Log(float64(n)) / math.Log(float64(max)) * float64(len(heatColors)-1))
+	if l >= len(heatColors) {
+		l = len(heatColors) - 1
+	}
+	return l
+}
+
+// heatClass returns the CSS class of a count.
+func heatClass(n, max int64) string {
+	if n == 0 {
+		return "cold"
+	}
+	return fmt.Sprintf("h%d", heatLevel(n, max))
+}
+
+// heatLegend returns the lowest count of each heat level used for counts up to max.
+func heatLegend(max int64) []htmlSegment {
+	var legend []htmlSegment
+	for i := range heatColors {
+		lo := int64(1)
+		if max > 1 {
+			lo = int64(math.Ceil(math.Pow(float64(max), float64(i)/float64(len(heatColors)-1))))
+		}
+		if heatLevel(lo, max) != i {
+			// no count has this level
+			continue
+		}
+		legend = append(legend, htmlSegment{Class: heatClass(lo, max), Text: fmt.Sprintf("≥%d", lo)})
+	}
+	return legend
+}
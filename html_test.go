@@ -0,0 +1,59 @@
+package bf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestProfile_WriteHTML(t *testing.T) {
+	c := qt.New(t)
+
+	var p Profile
+	bfi, err := New(strings.NewReader("++ two\n[>+<-][<]"), io.Discard, nil, WithProfile(&p))
+	c.Assert(err, qt.IsNil)
+	err = bfi.Exec()
+	c.Assert(err, qt.IsNil)
+
+	var buf bytes.Buffer
+	err = p.WriteHTML(&buf, "<prog>.bf")
+	c.Assert(err, qt.IsNil)
+	page := buf.String()
+
+	c.Assert(page, qt.Contains, "<title>&lt;prog&gt;.bf profile</title>")
+	c.Assert(page, qt.Contains, `<span class="h0" title="1 run">&#43;&#43;</span><span class="comment"> two`+"\n</span>")
+	c.Assert(page, qt.Contains, `<span class="h8" title="2 runs">&gt;&#43;&lt;-</span>`)
+	c.Assert(page, qt.Contains, `title="2 runs`+"\n"+`loop 2:1: 1 entries, 2 iterations, 2.0 per entry, 11 steps, `)
+	c.Assert(page, qt.Contains, "iterations per entry: 2-3:1\">]</span>")
+	c.Assert(page, qt.Contains, `<span class="cold" title="0 runs">&lt;</span>`)
+	c.Assert(page, qt.Contains, "loop 2:7: never entered")
+}
+
+func TestHeatLevel(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(heatLevel(1, 1), qt.Equals, 0)
+	c.Assert(heatLevel(1, 1000), qt.Equals, 0)
+	c.Assert(heatLevel(1000, 1000), qt.Equals, len(heatColors)-1)
+	c.Assert(heatClass(0, 1000), qt.Equals, "cold")
+
+	// the legend gives the lowest count of each level
+	max := int64(1000)
+	legend := heatLegend(max)
+	c.Assert(legend, qt.HasLen, len(heatColors))
+	for i, l := range legend {
+		var lo int64
+		_, err := fmt.Sscanf(l.Text, "≥%d", &lo)
+		c.Assert(err, qt.IsNil)
+		c.Assert(heatLevel(lo, max), qt.Equals, i)
+		if lo > 1 {
+			c.Assert(heatLevel(lo-1, max) < i, qt.IsTrue)
+		}
+	}
+
+	c.Assert(heatLegend(2), qt.DeepEquals, []htmlSegment{{Class: "h0", Text: "≥1"}, {Class: "h8", Text: "≥2"}})
+}
@@ -0,0 +1,73 @@
+// Package analyze provides functionality for the `analyze` command of the CLI
+package analyze
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf/analysis"
+)
+
+// errUnsafe is returned when a run may overflow a cell or fail
+var errUnsafe = errors.New("the program is not safe")
+
+// Cmd is the command for analyzing the cell values of BF programs
+func Cmd() *cobra.Command {
+	var c analysis.Config
+
+	cmd := &cobra.Command{
+		Use:   "analyze [--bits 8] [--signed] file_path",
+		Args:  cobra.ExactArgs(1),
+		Short: "Finds the possible values of the cells of a BF program without running it",
+		Long: `Analyzes the possible values of each cell and of the data pointer, whatever the
+inputs, and reports the commands that may overflow or underflow a cell, the loops that never
+run or never end, and the moves that always take the pointer left of the first cell. It fails
+if a run may overflow or underflow a cell, or move left of the first cell.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return analyze(args[0], c, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().IntVar(&c.Bits,
+		"bits", 8, "cell width in bits, up to 32")
+
+	cmd.Flags().BoolVar(&c.Signed,
+		"signed", false, "cells hold signed values")
+
+	return cmd
+}
+
+// analyze analyzes the program in file, and writes the findings and a summary to w
+func analyze(file string, c analysis.Config, w io.Writer) error {
+	if c.Bits < 1 || c.Bits > 32 {
+		return fmt.Errorf("invalid --bits %d: want 1 to 32", c.Bits)
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	r, err := analysis.Analyze(src, c)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	for _, f := range r.Findings {
+		fmt.Fprintf(w, "%s:%s\n", file, f)
+	}
+	if r.End {
+		fmt.Fprintf(w, "pointer at the end: %s\n", r.Pointer)
+	} else {
+		fmt.Fprintln(w, "the program never ends without an error")
+	}
+
+	if !r.Safe() {
+		return errUnsafe
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package analyze
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0o600)
+		c.Assert(err, qt.IsNil)
+		return path
+	}
+	safe := write("safe.bf", ",[>+<-]>.")
+	double := write("double.bf", ",[>++<-]")
+	stuck := write("stuck.bf", "+[]")
+	minus := write("minus.bf", "-.")
+
+	execute := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := Cmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := execute(safe)
+	c.Assert(err, qt.IsNil)
+	c.Assert(out, qt.Equals, "pointer at the end: 1\n")
+
+	out, err = execute(double)
+	c.Assert(err, qt.ErrorMatches, "the program is not safe")
+	c.Assert(out, qt.Equals, double+":1:4: '+' may overflow cell 1, which is in [0, 508]\n"+
+		double+":1:5: '+' may overflow cell 1, which is in [1, 509]\n"+
+		"pointer at the end: 0\n")
+
+	// safe on signed cells
+	_, err = execute(minus)
+	c.Assert(err, qt.ErrorMatches, "the program is not safe")
+	_, err = execute("--signed", minus)
+	c.Assert(err, qt.IsNil)
+
+	out, err = execute(stuck)
+	c.Assert(err, qt.IsNil)
+	c.Assert(out, qt.Equals, stuck+":1:2: the loop never ends once entered: cell 0 is never zero at its end\n"+
+		"the program never ends without an error\n")
+
+	_, err = execute("--bits", "64", safe)
+	c.Assert(err, qt.ErrorMatches, "invalid --bits 64: want 1 to 32")
+}
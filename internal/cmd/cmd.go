@@ -4,7 +4,20 @@ package cmd
 import (
 	"context"
 
+	"github.com/thesoulless/bf/internal/cmd/analyze"
+	"github.com/thesoulless/bf/internal/cmd/cover"
+	"github.com/thesoulless/bf/internal/cmd/dap"
+	"github.com/thesoulless/bf/internal/cmd/debug"
+	"github.com/thesoulless/bf/internal/cmd/equiv"
+	"github.com/thesoulless/bf/internal/cmd/explain"
+	"github.com/thesoulless/bf/internal/cmd/format"
+	"github.com/thesoulless/bf/internal/cmd/graph"
+	"github.com/thesoulless/bf/internal/cmd/lint"
+	"github.com/thesoulless/bf/internal/cmd/lsp"
+	"github.com/thesoulless/bf/internal/cmd/minify"
+	"github.com/thesoulless/bf/internal/cmd/profile"
 	"github.com/thesoulless/bf/internal/cmd/run"
+	"github.com/thesoulless/bf/internal/cmd/tracediff"
 
 	"github.com/spf13/cobra"
 )
@@ -30,6 +43,19 @@ func runCmd(ctx context.Context) error {
 	rootCmd.Flags().Bool("version", false, "show bf version")
 
 	rootCmd.AddCommand(run.Cmd())
+	rootCmd.AddCommand(debug.Cmd())
+	rootCmd.AddCommand(dap.Cmd())
+	rootCmd.AddCommand(lsp.Cmd())
+	rootCmd.AddCommand(tracediff.Cmd())
+	rootCmd.AddCommand(profile.Cmd())
+	rootCmd.AddCommand(cover.Cmd())
+	rootCmd.AddCommand(lint.Cmd())
+	rootCmd.AddCommand(analyze.Cmd())
+	rootCmd.AddCommand(graph.Cmd())
+	rootCmd.AddCommand(explain.Cmd())
+	rootCmd.AddCommand(equiv.Cmd())
+	rootCmd.AddCommand(format.Cmd())
+	rootCmd.AddCommand(minify.Cmd())
 
 	return rootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,146 @@
+// Package cover provides functionality for the `cover` command of the CLI
+package cover
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+)
+
+// options holds the flags of the cover command
+type options struct {
+	inputs []string
+	merge  []string
+	out    string
+}
+
+// Cmd is the command for measuring the coverage of BF programs
+func Cmd() *cobra.Command {
+	var o options
+
+	cmd := &cobra.Command{
+		Use:   "cover [-i input_file]... [-c coverage_file]... [-o coverage_file] file_path",
+		Args:  cobra.ExactArgs(1),
+		Short: "Reports which commands and loop branches of a BF program its inputs reach",
+		Long: `Runs the program once for each input file, or once reading stdin if neither inputs
+nor coverage files are given, merges the coverage with the given coverage files, and reports
+it. The program output is discarded.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cover(args[0], o, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&o.inputs,
+		"input", "i", nil, "file to read the program inputs (, command) from, for one run (repeatable)")
+
+	cmd.Flags().StringArrayVarP(&o.merge,
+		"coverage", "c", nil, "coverage file to merge, written by -o (repeatable)")
+
+	cmd.Flags().StringVarP(&o.out,
+		"out", "o", "", "write the merged coverage to a file")
+
+	return cmd
+}
+
+// cover records and merges the coverage of the program in file, and reports it to w.
+// Run errors are reported to errw, keeping the coverage of the failed run.
+func cover(file string, o options, w, errw io.Writer) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var cov bf.Coverage
+	run := func(name string, input io.Reader) error {
+		bfi, err := bf.New(bytes.NewReader(src), io.Discard, input, bf.WithCoverage(&cov))
+		if err != nil {
+			return err
+		}
+		// unlike Exec, Continue reports where the program failed
+		_, err = bfi.Continue()
+		if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Fprintf(errw, "error: %s: %v\n", name, err)
+		}
+		return nil
+	}
+
+	if len(o.inputs) == 0 && len(o.merge) == 0 {
+		err = run("stdin", os.Stdin)
+		if err != nil {
+			return err
+		}
+	}
+	for _, in := range o.inputs {
+		inp, err := os.ReadFile(in)
+		if err != nil {
+			return fmt.Errorf("failed to read input file: %w", err)
+		}
+		err = run(in, bytes.NewReader(inp))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range o.merge {
+		c, err := readCoverage(name)
+		if err != nil {
+			return err
+		}
+		if cov.Source == "" {
+			cov = *c
+			continue
+		}
+		err = cov.Merge(c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if o.out != "" {
+		err = writeCoverage(o.out, &cov)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = cov.Report(w, src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+func readCoverage(name string) (*bf.Coverage, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	c, err := bf.ReadCoverage(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+func writeCoverage(name string, c *bf.Coverage) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create the coverage file: %w", err)
+	}
+
+	err = c.Write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the coverage file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cover
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0o600)
+		c.Assert(err, qt.IsNil)
+		return path
+	}
+	prog := write("prog.bf", ",[>+<-]>[-]<[>]")
+	zero := write("zero", "0\n")
+	two := write("two", "2\n")
+
+	execute := func(args ...string) string {
+		var out bytes.Buffer
+		cmd := Cmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		err := cmd.Execute()
+		c.Assert(err, qt.IsNil)
+		return out.String()
+	}
+
+	zeroCov := filepath.Join(dir, "zero.json")
+	c.Assert(execute("-i", zero, "-o", zeroCov, prog), qt.Equals, `commands  6/15  40.0%
+branches  3/6   50.0%
+commands never run:
+  1:3-1:7
+  1:10-1:11
+  1:14-1:15
+branches never taken:
+  loop 1:2 never entered
+  loop 1:9 never entered
+  loop 1:13 never entered
+`)
+
+	// merged with the coverage of another input
+	c.Assert(execute("-i", two, "-c", zeroCov, prog), qt.Equals, `commands  13/15  86.7%
+branches  5/6    83.3%
+commands never run:
+  1:14-1:15
+branches never taken:
+  loop 1:13 never entered
+`)
+
+	// coverage files only
+	twoCov := filepath.Join(dir, "two.json")
+	execute("-i", two, "-o", twoCov, prog)
+	c.Assert(execute("-c", zeroCov, "-c", twoCov, prog), qt.Contains, "commands  13/15  86.7%\n")
+}
@@ -0,0 +1,68 @@
+// Package dap provides functionality for the `dap` command of the CLI
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf/internal/dap"
+)
+
+// Cmd is the command for serving the Debug Adapter Protocol
+func Cmd() *cobra.Command {
+	var listen string
+
+	cmd := &cobra.Command{
+		Use:   "dap [--listen address]",
+		Args:  cobra.ExactArgs(0),
+		Short: "Runs a Debug Adapter Protocol server, over stdio or TCP",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if listen == "" {
+				return dap.Serve(cmd.InOrStdin(), cmd.OutOrStdout())
+			}
+
+			l, err := net.Listen("tcp", listen)
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			go func() {
+				<-cmd.Context().Done()
+				l.Close()
+			}()
+			fmt.Fprintf(os.Stderr, "listening on %s\n", l.Addr())
+
+			return serve(l)
+		},
+	}
+
+	cmd.Flags().StringVarP(&listen,
+		"listen", "l", "", "TCP address to listen on, e.g. localhost:4711 (default is stdio)")
+
+	return cmd
+}
+
+// serve runs a debug session for each connection accepted by l, until l is closed
+func serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			err := dap.Serve(conn, conn)
+			if err != nil {
+				log.Printf("debug session failed: %v", err)
+			}
+		}(conn)
+	}
+}
@@ -0,0 +1,440 @@
+// Package debug provides functionality for the `debug` command of the CLI
+package debug
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/sourcemap"
+)
+
+// window is the number of cells printed on each side of the data pointer by print
+const window = 4
+
+// maxCells is the largest number of cells printed by print
+const maxCells = 256
+
+// context is the number of source lines printed on each side of the current line by list
+const context = 3
+
+const help = `commands:
+  step, s [n]           run the next n commands (default 1)
+  next, n               like step, but run a whole loop when the next command opens one
+  continue, c           run until a breakpoint, a watchpoint or the end of the program
+  back, rs              revert the last command
+  rcontinue, rc         revert commands until a breakpoint or a watchpoint
+  break, b line:col     set a breakpoint, or at file:line[:col] of a source map
+  delete, d line:col    remove a breakpoint
+  watch, w expr         set a watchpoint, e.g. cell[5]==0 or ptr in 10..20
+  unwatch id            remove a watchpoint
+  info, i               list the breakpoints and the watchpoints
+  print, p [i | i..j]   print up to 256 cells, around the data pointer by default
+  list, l               show the source around the next command
+  stack, bt             show the loop stack
+  restart, r            run the program from the start, keeping breakpoints and watchpoints
+  help, h               show this help
+  quit, q               exit the debugger`
+
+// Cmd is the command for debugging BF programs
+func Cmd() *cobra.Command {
+	var file string
+	var input string
+	var sourceMap string
+
+	cmd := &cobra.Command{
+		Use:   "debug -f file_path [-i input_file]",
+		Args:  cobra.ExactArgs(0),
+		Short: "Debugs a BF program with a gdb-like prompt",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read file: %w", err)
+			}
+
+			var inp []byte
+			if input != "" {
+				inp, err = os.ReadFile(input)
+				if err != nil {
+					return fmt.Errorf("failed to read input file: %w", err)
+				}
+			}
+
+			var m *sourcemap.Map
+			if sourceMap != "" {
+				m, err = sourcemap.ReadFile(sourceMap)
+				if err != nil {
+					return fmt.Errorf("failed to read the source map: %w", err)
+				}
+			}
+
+			d, err := newDebugger(src, inp, m, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return d.run(cmd.InOrStdin())
+		},
+	}
+
+	cmd.Flags().StringVarP(&file,
+		"file", "f", "", "BF file path")
+
+	cmd.Flags().StringVarP(&input,
+		"input", "i", "", "file to read the program inputs (, command) from")
+
+	cmd.Flags().StringVar(&sourceMap,
+		"source-map", "", "source map file, to show the positions and the lines of the original sources")
+
+	return cmd
+}
+
+// debugger drives a bf.BF from the commands typed at the prompt
+type debugger struct {
+	src  []byte
+	inp  []byte
+	smap *sourcemap.Map // nil without a source map
+	out  io.Writer
+
+	bfi     *bf.BF
+	lines   []string
+	sources map[string][]string // lines of the original sources, nil if unreadable
+}
+
+func newDebugger(src, inp []byte, m *sourcemap.Map, out io.Writer) (*debugger, error) {
+	d := &debugger{src: src, inp: inp, smap: m, out: out, sources: make(map[string][]string)}
+	d.lines = strings.Split(string(src), "\n")
+
+	return d, d.restart()
+}
+
+// restart creates a new interpreter, keeping the breakpoints and the watchpoints
+func (d *debugger) restart() error {
+	opts := []bf.Option{bf.WithHistory(0)}
+	if d.smap != nil {
+		opts = append(opts, bf.WithSourceMap(d.smap))
+	}
+	bfi, err := bf.New(bytes.NewReader(d.src), d.out, bytes.NewReader(d.inp), opts...)
+	if err != nil {
+		return err
+	}
+
+	if d.bfi != nil {
+		for _, p := range d.bfi.Breakpoints() {
+			_, _ = bfi.SetBreakpoint(p.Offset)
+		}
+		for _, w := range d.bfi.Watchpoints() {
+			bfi.AddWatchpoint(w)
+		}
+	}
+
+	d.bfi = bfi
+	return nil
+}
+
+// run reads commands from in until it is closed or quit is typed
+func (d *debugger) run(in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	d.where()
+
+	for {
+		fmt.Fprint(d.out, "(bf) ")
+		if !sc.Scan() {
+			fmt.Fprintln(d.out)
+			return sc.Err()
+		}
+
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "q" {
+			return nil
+		}
+
+		err := d.exec(fields[0], strings.TrimSpace(strings.TrimPrefix(sc.Text(), fields[0])))
+		if err != nil {
+			fmt.Fprintf(d.out, "error: %v\n", err)
+		}
+	}
+}
+
+// exec runs one debugger command with its argument
+func (d *debugger) exec(cmd, arg string) error {
+	switch cmd {
+	case "step", "s":
+		n := 1
+		if arg != "" {
+			var err error
+			n, err = strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid count %q", arg)
+			}
+		}
+		for i := 0; i < n; i++ {
+			_, err := d.bfi.Step()
+			if err != nil {
+				return d.stopped(err)
+			}
+			if len(d.bfi.WatchHits()) > 0 {
+				break
+			}
+		}
+		return d.stopped(nil)
+	case "next", "n":
+		_, err := d.bfi.StepOver()
+		return d.stopped(err)
+	case "continue", "c":
+		_, err := d.bfi.Continue()
+		return d.stopped(err)
+	case "back", "rs":
+		_, err := d.bfi.StepBack()
+		return d.stopped(err)
+	case "rcontinue", "rc":
+		_, err := d.bfi.ReverseContinue()
+		return d.stopped(err)
+	case "break", "b":
+		off, err := d.offset(arg)
+		if err != nil {
+			return err
+		}
+		p, err := d.bfi.SetBreakpoint(off)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(d.out, "breakpoint at %s\n", pos(p))
+	case "delete", "d":
+		off, err := d.offset(arg)
+		if err != nil {
+			return err
+		}
+		if !d.bfi.ClearBreakpoint(off) {
+			return fmt.Errorf("no breakpoint at %s", arg)
+		}
+	case "watch", "w":
+		w, err := bf.ParseWatchpoint(arg)
+		if err != nil {
+			return err
+		}
+		id := d.bfi.AddWatchpoint(w)
+		fmt.Fprintf(d.out, "watchpoint %d: %s\n", id, w)
+	case "unwatch":
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid watchpoint id %q", arg)
+		}
+		d.bfi.RemoveWatchpoint(id)
+	case "info", "i":
+		for _, p := range d.bfi.Breakpoints() {
+			fmt.Fprintf(d.out, "breakpoint at %s\n", pos(p))
+		}
+		for _, w := range d.bfi.Watchpoints() {
+			fmt.Fprintf(d.out, "watchpoint %d: %s\n", w.ID, w)
+		}
+	case "print", "p":
+		return d.print(arg)
+	case "list", "l":
+		d.list()
+	case "stack", "bt":
+		loops := d.bfi.LoopStack()
+		if len(loops) == 0 {
+			fmt.Fprintln(d.out, "not in a loop")
+		}
+		for i := len(loops) - 1; i >= 0; i-- {
+			fmt.Fprintf(d.out, "#%d loop at %s\n", len(loops)-1-i, pos(loops[i]))
+		}
+	case "restart", "r":
+		err := d.restart()
+		if err != nil {
+			return err
+		}
+		d.where()
+	case "help", "h":
+		fmt.Fprintln(d.out, help)
+	default:
+		return fmt.Errorf("unknown command %q, type help for the list of commands", cmd)
+	}
+
+	return nil
+}
+
+// stopped reports where the program stopped and why
+func (d *debugger) stopped(err error) error {
+	switch {
+	case errors.Is(err, io.EOF):
+		fmt.Fprintln(d.out, "program finished")
+		return nil
+	case errors.Is(err, bf.ErrNoHistory):
+		fmt.Fprintln(d.out, "at the start of the recorded history")
+	case err != nil:
+		return err
+	}
+
+	for _, h := range d.bfi.WatchHits() {
+		fmt.Fprintf(d.out, "watchpoint %d %s\n", h.Watchpoint.ID, h)
+	}
+	d.where()
+	return nil
+}
+
+// where prints the next command and the machine state
+func (d *debugger) where() {
+	if d.bfi.Done() {
+		fmt.Fprintln(d.out, "program finished")
+		return
+	}
+
+	p := d.bfi.Pos()
+	fmt.Fprintf(d.out, "%s %q ptr=%d cell=%d\n", pos(p), d.src[p.Offset], d.bfi.Pointer(), d.bfi.Cell(d.bfi.Pointer()))
+}
+
+// pos formats p, followed by its original position if there is one
+func pos(p bf.Position) string {
+	if p.Orig == nil {
+		return p.String()
+	}
+	return fmt.Sprintf("%s (%s)", p, p.Orig)
+}
+
+// list prints the source lines around the next command, marking it with a caret. With
+// a source map, the lines of the original source are printed, if it can be read.
+func (d *debugger) list() {
+	p := d.bfi.Pos()
+	if d.bfi.Done() {
+		p = d.bfi.Position(len(d.src) - 1)
+	}
+
+	lines, line, col := d.lines, p.Line, p.Column
+	if o := p.Orig; o != nil {
+		if src := d.source(o.File); src != nil {
+			fmt.Fprintf(d.out, "%s:\n", o.File)
+			lines, line, col = src, o.Line, o.Column
+		}
+	}
+
+	from, to := line-context, line+context
+	if from < 1 {
+		from = 1
+	}
+	if to > len(lines) {
+		to = len(lines)
+	}
+
+	for l := from; l <= to; l++ {
+		fmt.Fprintf(d.out, "%4d | %s\n", l, lines[l-1])
+		if l == line && !d.bfi.Done() {
+			fmt.Fprintf(d.out, "     | %s^\n", strings.Repeat(" ", col-1))
+		}
+	}
+}
+
+// source returns the lines of the original source file, or nil if it can not be read
+func (d *debugger) source(file string) []string {
+	lines, ok := d.sources[file]
+	if !ok {
+		src, err := os.ReadFile(file)
+		if err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		d.sources[file] = lines
+	}
+	return lines
+}
+
+// print prints a window of cells: i, i..j, or around the data pointer
+func (d *debugger) print(arg string) error {
+	ptr := d.bfi.Pointer()
+	from, to := ptr-window, ptr+window
+	if from < 0 {
+		from = 0
+	}
+
+	if arg != "" {
+		a, b, isRange := strings.Cut(arg, "..")
+		var err error
+		from, err = strconv.Atoi(strings.TrimSpace(a))
+		if err != nil || from < 0 {
+			return fmt.Errorf("invalid cell %q", a)
+		}
+		to = from
+		if isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(b))
+			if err != nil || to < from {
+				return fmt.Errorf("invalid cell %q", b)
+			}
+		}
+	}
+	if to-from >= maxCells {
+		fmt.Fprintf(d.out, "printing the first %d cells of %d..%d\n", maxCells, from, to)
+		to = from + maxCells - 1
+	}
+
+	for i := from; i <= to; i++ {
+		mark := " "
+		if i == ptr {
+			mark = ">"
+		}
+		fmt.Fprintf(d.out, "%s cell[%d] = %d\n", mark, i, d.bfi.Cell(i))
+	}
+
+	return nil
+}
+
+// offset converts a line:col argument, a plain offset, or a file:line[:col] position in
+// an original source, to a source offset
+func (d *debugger) offset(arg string) (int, error) {
+	if d.smap != nil {
+		if file, line, col, ok := sourcePos(arg); ok {
+			return d.bfi.SourceOffset(file, line, col)
+		}
+	}
+
+	l, c, ok := strings.Cut(arg, ":")
+	if !ok {
+		off, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, fmt.Errorf("invalid position %q, want line:col", arg)
+		}
+		return off, nil
+	}
+
+	line, err := strconv.Atoi(l)
+	if err != nil {
+		return 0, fmt.Errorf("invalid line %q", l)
+	}
+	col, err := strconv.Atoi(c)
+	if err != nil {
+		return 0, fmt.Errorf("invalid column %q", c)
+	}
+
+	return d.bfi.Offset(line, col)
+}
+
+// sourcePos parses a file:line[:col] argument, where file is not a number
+func sourcePos(arg string) (file string, line, col int, ok bool) {
+	parts := strings.Split(arg, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", 0, 0, false
+	}
+	if _, err := strconv.Atoi(parts[0]); err == nil {
+		return "", 0, 0, false
+	}
+
+	line, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	if len(parts) == 3 {
+		col, err = strconv.Atoi(parts[2])
+		if err != nil {
+			return "", 0, 0, false
+		}
+	}
+	return parts[0], line, col, true
+}
@@ -0,0 +1,155 @@
+package debug
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/sourcemap"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("session", func(t *testing.T) {
+		file := "../../../testdata/test.bf"
+
+		cmd := Cmd()
+		err := cmd.Flags().Set("file", file)
+		c.Assert(err, qt.IsNil)
+		cmd.Args = nil
+
+		in := strings.NewReader(`break 2:3
+continue
+print 1..3
+stack
+list
+watch cell[2]==72
+c
+info
+restart
+c
+q
+`)
+		var out bytes.Buffer
+		cmd.SetIn(in)
+		cmd.SetOut(&out)
+
+		err = cmd.Execute()
+		c.Assert(err, qt.IsNil)
+		c.Assert(out.String(), qt.Equals, `1:1 '+' ptr=0 cell=0
+(bf) breakpoint at 2:3
+(bf) 2:3 '.' ptr=2 cell=72
+(bf)   cell[1] = 0
+> cell[2] = 72
+  cell[3] = 104
+(bf) not in a loop
+(bf)    1 | ++++++++[>++++[>++>+++>+++>+<<<<-]>+>+>->>+[<]<-]
+   2 | >>.>---.+++++++..+++.>>.<-.<.
+     |   ^
+   3 | +++.------.--------.>>+.>++.
+(bf) watchpoint 1: cell[2]==72
+(bf) Hello World!
+program finished
+(bf) breakpoint at 2:3
+watchpoint 1: cell[2]==72
+(bf) 1:1 '+' ptr=0 cell=0
+(bf) watchpoint 1 cell[2]==72 at 1:36: 71 -> 72
+1:37 '>' ptr=2 cell=72
+(bf) `)
+	})
+}
+
+func TestDebugger(t *testing.T) {
+	c := qt.New(t)
+
+	var out bytes.Buffer
+	d, err := newDebugger([]byte("+[>+<-]>."), nil, nil, &out)
+	c.Assert(err, qt.IsNil)
+
+	for _, cmd := range []struct{ name, arg string }{
+		{"step", "2"}, {"stack", ""}, {"back", ""}, {"next", ""}, {"print", "1"}, {"rc", ""},
+	} {
+		err := d.exec(cmd.name, cmd.arg)
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(out.String(), qt.Equals, `1:3 '>' ptr=0 cell=1
+#0 loop at 1:2
+1:2 '[' ptr=0 cell=1
+1:8 '>' ptr=0 cell=0
+  cell[1] = 1
+at the start of the recorded history
+1:1 '+' ptr=0 cell=0
+`)
+
+	// a large range is cut to the first cells
+	out.Reset()
+	err = d.exec("print", "0..100000000")
+	c.Assert(err, qt.IsNil)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	c.Assert(lines, qt.HasLen, maxCells+1)
+	c.Assert(lines[0], qt.Equals, "printing the first 256 cells of 0..100000000")
+	c.Assert(lines[maxCells], qt.Equals, "  cell[255] = 0")
+
+	err = d.exec("break", "x")
+	c.Assert(err, qt.ErrorMatches, `invalid position "x", want line:col`)
+	err = d.exec("delete", "1:3")
+	c.Assert(err, qt.ErrorMatches, `no breakpoint at 1:3`)
+	err = d.exec("nope", "")
+	c.Assert(err, qt.ErrorMatches, `unknown command "nope".*`)
+}
+
+func TestDebugger_SourceMap(t *testing.T) {
+	c := qt.New(t)
+
+	orig := filepath.Join(t.TempDir(), "orig.bf")
+	src := "+ add\n[->+<]\n>."
+	err := os.WriteFile(orig, []byte(src), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	// the program is the original without its comments and line ends
+	var prog []byte
+	var mappings []sourcemap.Mapping
+	line, col := 1, 1
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			line, col = line+1, 1
+			continue
+		}
+		if strings.IndexByte("+-<>[].,", src[i]) >= 0 {
+			prog = append(prog, src[i])
+			mappings = append(mappings, sourcemap.Mapping{
+				Line: 1, Column: len(prog), SourceLine: line, SourceColumn: col,
+			})
+		}
+		col++
+	}
+	m := sourcemap.New("", []string{orig}, mappings)
+
+	var out bytes.Buffer
+	d, err := newDebugger(prog, nil, m, &out)
+	c.Assert(err, qt.IsNil)
+
+	for _, cmd := range []struct{ name, arg string }{
+		{"break", orig + ":2:3"}, {"continue", ""}, {"stack", ""}, {"list", ""},
+	} {
+		err := d.exec(cmd.name, cmd.arg)
+		c.Assert(err, qt.IsNil)
+	}
+	c.Assert(out.String(), qt.Equals, `breakpoint at 1:4 (`+orig+`:2:3)
+1:4 (`+orig+`:2:3) '>' ptr=0 cell=0
+#0 loop at 1:2 (`+orig+`:2:1)
+`+orig+`:
+   1 | + add
+   2 | [->+<]
+     |   ^
+   3 | >.
+`)
+
+	err = d.exec("break", orig+":1:2")
+	c.Assert(err, qt.ErrorIs, bf.ErrInvalidPosition)
+}
@@ -0,0 +1,102 @@
+// Package equiv provides functionality for the `equiv` command of the CLI
+package equiv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/equiv"
+)
+
+// errDiffer is returned when the programs behave differently on an input
+var errDiffer = errors.New("the programs are not equivalent")
+
+// Cmd is the command for checking that two BF programs behave the same
+func Cmd() *cobra.Command {
+	var c equiv.Config
+
+	cmd := &cobra.Command{
+		Use:   "equiv [--inputs 2] [--min 0] [--max 255] [--steps 100000] a.bf b.bf",
+		Args:  cobra.ExactArgs(2),
+		Short: "Checks that two BF programs write the same output for every input up to a bound",
+		Long: `Runs two BF programs on every input of up to --inputs values, each value between --min
+and --max, and checks that they write the same output and end, or fail with the same error.
+Each program runs for at most --steps commands on each input. It prints the first input on
+which the programs differ, the shortest first, and fails if there is one.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return check(args[0], args[1], c, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().IntVar(&c.Inputs,
+		"inputs", 2, "most input values given to a run")
+
+	cmd.Flags().Int32Var(&c.Min,
+		"min", 0, "smallest input value")
+
+	cmd.Flags().Int32Var(&c.Max,
+		"max", 255, "largest input value")
+
+	cmd.Flags().Int64Var(&c.Steps,
+		"steps", equiv.DefaultSteps, "most commands run by a program on an input")
+
+	return cmd
+}
+
+// check checks the programs in the files a and b, and writes the result to w
+func check(a, b string, c equiv.Config, w io.Writer) error {
+	if c.Steps <= 0 {
+		return fmt.Errorf("invalid --steps %d: want at least 1", c.Steps)
+	}
+
+	var srcs [2][]byte
+	for i, file := range []string{a, b} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		_, err = bf.New(bytes.NewReader(src), io.Discard, strings.NewReader(""))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		srcs[i] = src
+	}
+
+	r, err := equiv.Check(srcs[0], srcs[1], c)
+	if err != nil {
+		return err
+	}
+
+	if d := r.Diff; d != nil {
+		fmt.Fprintf(w, "the programs differ on the input %s\n", input(d.Input))
+		fmt.Fprintf(w, "  %s: %s\n", a, d.A)
+		fmt.Fprintf(w, "  %s: %s\n", b, d.B)
+		return errDiffer
+	}
+
+	fmt.Fprintf(w, "the programs behave the same on %d inputs of up to %d values in [%d, %d]\n",
+		r.Cases, c.Inputs, c.Min, c.Max)
+	if r.Incomplete > 0 {
+		fmt.Fprintf(w, "%d inputs ran out of steps, the programs may still differ on them\n", r.Incomplete)
+	}
+	return nil
+}
+
+// input returns the values of an input, one per line as the programs read them
+func input(v []int32) string {
+	if len(v) == 0 {
+		return "with no values"
+	}
+	s := make([]string, len(v))
+	for i, x := range v {
+		s[i] = fmt.Sprint(x)
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,52 @@
+package equiv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.bf": ",[->+<]>.",
+		"b.bf": ",>[-]<[->+<]>.",
+		"c.bf": ",[->+<]>+.",
+		"d.bf": "[",
+	}
+	for name, src := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o600)
+		c.Assert(err, qt.IsNil)
+	}
+	a, b := filepath.Join(dir, "a.bf"), filepath.Join(dir, "b.bf")
+
+	var out bytes.Buffer
+	cmd := Cmd()
+	cmd.SetArgs([]string{"--inputs", "1", "--max", "9", a, b})
+	cmd.SetOut(&out)
+	err := cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "the programs behave the same on 11 inputs of up to 1 values in [0, 9]\n")
+
+	out.Reset()
+	cmd = Cmd()
+	cmd.SetArgs([]string{"--min", "3", a, filepath.Join(dir, "c.bf")})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.Equals, errDiffer)
+	c.Assert(out.String(), qt.Equals, `the programs differ on the input with no values
+  `+a+`: output "\x00"
+  `+filepath.Join(dir, "c.bf")+`: output "\x01"
+`)
+
+	cmd = Cmd()
+	cmd.SetArgs([]string{a, filepath.Join(dir, "d.bf")})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, ".*d.bf: .*")
+}
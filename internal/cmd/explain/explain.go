@@ -0,0 +1,47 @@
+// Package explain provides functionality for the `explain` command of the CLI
+package explain
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// Cmd is the command for decompiling BF programs into pseudocode
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain file_path",
+		Args:  cobra.ExactArgs(1),
+		Short: "Decompiles a BF program into readable pseudocode",
+		Long: `Decompiles a BF program into pseudocode, with the source range of each statement. The
+cells are c[p], c[p+1] and so on, relative to the data pointer p. Runs of commands become
+a single statement, loops become while loops, and common loops are recognized:
+
+	[-]       c[p] = 0
+	[->+<]    c[p+1] += c[p]; c[p] = 0
+	[>]       while c[p] != 0 { p += 1 }`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return explain(args[0], cmd.OutOrStdout())
+		},
+	}
+
+	return cmd
+}
+
+// explain writes the pseudocode of the program in file to w
+func explain(file string, w io.Writer) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	f, err := syntax.Parse(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	return writePseudo(w, f)
+}
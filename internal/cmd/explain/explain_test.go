@@ -0,0 +1,41 @@
+package explain
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "prog.bf")
+	err := os.WriteFile(prog, []byte(",[->++<]>."), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	var out bytes.Buffer
+	cmd := Cmd()
+	cmd.SetArgs([]string{prog})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, `c[p] = in()                   // 1:1
+c[p+1] += 2 * c[p]; c[p] = 0  // 1:2-1:8
+p += 1                        // 1:9
+out(c[p])                     // 1:10
+`)
+
+	bad := filepath.Join(dir, "bad.bf")
+	err = os.WriteFile(bad, []byte("+]"), 0o600)
+	c.Assert(err, qt.IsNil)
+	cmd = Cmd()
+	cmd.SetArgs([]string{bad})
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	err = cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, ".*bad.bf: .*")
+}
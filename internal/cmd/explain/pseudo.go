@@ -0,0 +1,192 @@
+package explain
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// indent is the indentation of each loop level.
+const indent = "    "
+
+// printer writes the pseudocode of a parsed source, one statement per line, followed by
+// the source range it comes from.
+type printer struct {
+	w *tabwriter.Writer
+	f *syntax.File
+}
+
+// writePseudo writes the pseudocode of f to w.
+func writePseudo(w io.Writer, f *syntax.File) error {
+	p := &printer{w: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0), f: f}
+	p.nodes(f.Nodes, "")
+	return p.w.Flush()
+}
+
+func (p *printer) line(prefix, stmt string, from, to int) {
+	fmt.Fprintf(p.w, "%s%s\t// %s\n", prefix, stmt, p.f.Span(from, to))
+}
+
+// nodes writes nodes at the indentation prefix. Consecutive commands of the same kind
+// are grouped into a single statement.
+func (p *printer) nodes(nodes []syntax.Node, prefix string) {
+	for i := 0; i < len(nodes); {
+		switch n := nodes[i].(type) {
+		case *syntax.Loop:
+			p.loop(n, prefix)
+			i++
+		case *syntax.Command:
+			j := i + 1
+			if n.Op != '.' && n.Op != ',' {
+				for j < len(nodes) && sameKind(nodes[j], n.Op) {
+					j++
+				}
+			}
+			if stmt := run(nodes[i:j]); stmt != "" {
+				p.line(prefix, stmt, n.Offset, nodes[j-1].Pos())
+			}
+			i = j
+		}
+	}
+}
+
+func (p *printer) loop(l *syntax.Loop, prefix string) {
+	if stmt, ok := idiom(l); ok {
+		p.line(prefix, stmt, l.Open, l.Close)
+		return
+	}
+	p.line(prefix, "while c[p] != 0 {", l.Open, l.Close)
+	p.nodes(l.Body, prefix+indent)
+	p.line(prefix, "}", l.Close, l.Close)
+}
+
+// sameKind reports whether n is a command that can be grouped with op: both change the
+// current cell, or both move the pointer.
+func sameKind(n syntax.Node, op byte) bool {
+	c, ok := n.(*syntax.Command)
+	if !ok {
+		return false
+	}
+	return kind(c.Op) == kind(op)
+}
+
+func kind(op byte) byte {
+	switch op {
+	case '+', '-':
+		return '+'
+	case '>', '<':
+		return '>'
+	}
+	return op
+}
+
+// run returns the statement of a group of commands of the same kind, or "" if they
+// cancel out.
+func run(nodes []syntax.Node) string {
+	op := nodes[0].(*syntax.Command).Op
+	switch op {
+	case '.':
+		return "out(c[p])"
+	case ',':
+		return "c[p] = in()"
+	}
+	n := 0
+	for _, c := range nodes {
+		switch c.(*syntax.Command).Op {
+		case '+', '>':
+			n++
+		case '-', '<':
+			n--
+		}
+	}
+	if kind(op) == '>' {
+		return add("p", n)
+	}
+	return add("c[p]", n)
+}
+
+// add returns the statement adding n to x, or "" if n is zero.
+func add(x string, n int) string {
+	switch {
+	case n > 0:
+		return fmt.Sprintf("%s += %d", x, n)
+	case n < 0:
+		return fmt.Sprintf("%s -= %d", x, -n)
+	}
+	return ""
+}
+
+// idiom returns a single statement for the loops with a known meaning: clearing the
+// current cell, adding multiples of it to other cells while counting it down to zero, and
+// moving the pointer by a fixed step until a zero cell.
+func idiom(l *syntax.Loop) (string, bool) {
+	d := map[int]int{} // cell changes per iteration, by offset
+	ptr := 0
+	for _, n := range l.Body {
+		c, ok := n.(*syntax.Command)
+		if !ok {
+			return "", false
+		}
+		switch c.Op {
+		case '>':
+			ptr++
+		case '<':
+			ptr--
+		case '+':
+			d[ptr]++
+		case '-':
+			d[ptr]--
+		default:
+			return "", false
+		}
+	}
+
+	if ptr != 0 {
+		for _, v := range d {
+			if v != 0 {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("while c[p] != 0 { %s }", add("p", ptr)), true
+	}
+	if d[0] != -1 && (d[0] != 1 || len(d) > 1) {
+		return "", false
+	}
+
+	offs := make([]int, 0, len(d))
+	for off, v := range d {
+		if off != 0 && v != 0 {
+			offs = append(offs, off)
+		}
+	}
+	sort.Ints(offs)
+	var stmts []string
+	for _, off := range offs {
+		switch v := d[off]; {
+		case v == 1:
+			stmts = append(stmts, fmt.Sprintf("%s += c[p]", cell(off)))
+		case v == -1:
+			stmts = append(stmts, fmt.Sprintf("%s -= c[p]", cell(off)))
+		case v > 0:
+			stmts = append(stmts, fmt.Sprintf("%s += %d * c[p]", cell(off), v))
+		default:
+			stmts = append(stmts, fmt.Sprintf("%s -= %d * c[p]", cell(off), -v))
+		}
+	}
+	stmts = append(stmts, "c[p] = 0")
+	return strings.Join(stmts, "; "), true
+}
+
+func cell(off int) string {
+	switch {
+	case off > 0:
+		return fmt.Sprintf("c[p+%d]", off)
+	case off < 0:
+		return fmt.Sprintf("c[p-%d]", -off)
+	}
+	return "c[p]"
+}
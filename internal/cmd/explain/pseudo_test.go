@@ -0,0 +1,67 @@
+package explain
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+func TestWritePseudo(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := syntax.Parse([]byte("+++++ add 5\n[>+.-,<+-<-]>>.."))
+	c.Assert(err, qt.IsNil)
+
+	var buf bytes.Buffer
+	err = writePseudo(&buf, f)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `c[p] += 5          // 1:1-1:5
+while c[p] != 0 {  // 2:1-2:12
+    p += 1         // 2:2
+    c[p] += 1      // 2:3
+    out(c[p])      // 2:4
+    c[p] -= 1      // 2:5
+    c[p] = in()    // 2:6
+    p -= 1         // 2:7
+    p -= 1         // 2:10
+    c[p] -= 1      // 2:11
+}                  // 2:12
+p += 2             // 2:13-2:14
+out(c[p])          // 2:15
+out(c[p])          // 2:16
+`)
+}
+
+func TestIdiom(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"[-]", "c[p] = 0"},
+		{"[+]", "c[p] = 0"},
+		{"[->+<]", "c[p+1] += c[p]; c[p] = 0"},
+		{"[<->-]", "c[p-1] -= c[p]; c[p] = 0"},
+		{"[>>+++<-<<-->+--]", "c[p-1] -= 2 * c[p]; c[p+1] -= c[p]; c[p+2] += 3 * c[p]; c[p] = 0"},
+		{"[>]", "while c[p] != 0 { p += 1 }"},
+		{"[<<]", "while c[p] != 0 { p -= 2 }"},
+		{"[>+<]", ""},
+		{"[+>+<]", ""},
+		{"[->+<.]", ""},
+		{"[-[-]]", ""},
+		{"[>-]", ""},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.src, func(t *testing.T) {
+			c := qt.New(t)
+			f, err := syntax.Parse([]byte(test.src))
+			c.Assert(err, qt.IsNil)
+
+			got, ok := idiom(f.Nodes[0].(*syntax.Loop))
+			c.Assert(ok, qt.Equals, test.want != "")
+			c.Assert(got, qt.Equals, test.want)
+		})
+	}
+}
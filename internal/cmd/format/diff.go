@@ -0,0 +1,129 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// context is the number of unchanged lines around the changes of a diff.
+const context = 3
+
+// edit is a line of a diff: kept (' '), removed ('-') or added ('+').
+type edit struct {
+	kind byte
+	text []byte
+}
+
+// diff writes the unified diff from a to b, with name as the name of both files.
+func diff(w io.Writer, name string, a, b []byte) error {
+	edits := lineEdits(lines(a), lines(b))
+
+	fmt.Fprintf(w, "diff %s.orig %s\n", name, name)
+	fmt.Fprintf(w, "--- %s.orig\n", name)
+	fmt.Fprintf(w, "+++ %s\n", name)
+
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// the hunk goes on while the changes are at most 2*context lines apart
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(edits) && j-end <= 2*context; j++ {
+			if edits[j].kind != ' ' {
+				end = j
+			}
+		}
+		end += context + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		la, lb := 1, 1 // line numbers in a and b of the start of the hunk
+		for _, e := range edits[:start] {
+			if e.kind != '+' {
+				la++
+			}
+			if e.kind != '-' {
+				lb++
+			}
+		}
+		na, nb := 0, 0
+		for _, e := range edits[start:end] {
+			if e.kind != '+' {
+				na++
+			}
+			if e.kind != '-' {
+				nb++
+			}
+		}
+		if na == 0 {
+			la--
+		}
+		if nb == 0 {
+			lb--
+		}
+
+		fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", la, na, lb, nb)
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(w, "%c%s\n", e.kind, e.text)
+		}
+		i = end
+	}
+	return nil
+}
+
+// lines splits src into lines, without the line ends.
+func lines(src []byte) [][]byte {
+	src = bytes.TrimSuffix(src, []byte("\n"))
+	if len(src) == 0 {
+		return nil
+	}
+	return bytes.Split(src, []byte("\n"))
+}
+
+// lineEdits returns the shortest edits from a to b, from their longest common
+// subsequence of lines.
+func lineEdits(a, b [][]byte) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if bytes.Equal(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = lcs[i+1][j]
+				if lcs[i][j+1] > lcs[i][j] {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && bytes.Equal(a[i], b[j]):
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	return edits
+}
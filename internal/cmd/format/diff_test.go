@@ -0,0 +1,42 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestDiff(t *testing.T) {
+	c := qt.New(t)
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	var buf bytes.Buffer
+	err := diff(&buf, "a.bf", []byte(a), []byte(b))
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `diff a.bf.orig a.bf
+--- a.bf.orig
++++ a.bf
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`)
+
+	buf.Reset()
+	err = diff(&buf, "a.bf", nil, []byte("+\n"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, "diff a.bf.orig a.bf\n--- a.bf.orig\n+++ a.bf\n@@ -0,0 +1,1 @@\n++\n")
+}
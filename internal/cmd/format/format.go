@@ -0,0 +1,92 @@
+// Package format provides functionality for the `fmt` command of the CLI
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf/format"
+)
+
+// options holds the flags of the fmt command
+type options struct {
+	write bool
+	diff  bool
+	c     format.Config
+}
+
+// Cmd is the command for formatting BF sources
+func Cmd() *cobra.Command {
+	var o options
+
+	cmd := &cobra.Command{
+		Use:   "fmt [-w] [-d] [--width 60] files...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Formats BF sources in the canonical style",
+		Long: `Formats BF sources: loops that hold other loops or comments are indented by their
+depth, runs of commands are packed on lines of up to --width commands, and comments stay
+next to the commands they follow. By default, the formatted sources are written to the
+standard output.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, file := range args {
+				err := formatFile(file, o, cmd.OutOrStdout())
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&o.write,
+		"write", "w", false, "write the result to the source file instead of the standard output")
+
+	cmd.Flags().BoolVarP(&o.diff,
+		"diff", "d", false, "write diffs instead of the formatted sources")
+
+	cmd.Flags().IntVar(&o.c.Width,
+		"width", format.DefaultWidth, "number of commands on a line")
+
+	cmd.Flags().StringVar(&o.c.Indent,
+		"indent", format.DefaultIndent, "indentation of each loop level")
+
+	return cmd
+}
+
+// formatFile formats file, and writes it back, or writes the result or the diff to w
+func formatFile(file string, o options, w io.Writer) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	res, err := format.Source(src, o.c)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	if o.diff && !bytes.Equal(src, res) {
+		err = diff(w, file, src, res)
+		if err != nil {
+			return err
+		}
+	}
+	if o.write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(file, res, fi.Mode().Perm())
+	}
+	if !o.diff {
+		_, err = w.Write(res)
+	}
+	return err
+}
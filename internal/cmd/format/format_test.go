@@ -0,0 +1,59 @@
+package format
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.bf")
+	err := os.WriteFile(a, []byte("+[->+<] move\n"), 0o600)
+	c.Assert(err, qt.IsNil)
+	b := filepath.Join(dir, "b.bf")
+	err = os.WriteFile(b, []byte("++ [ ->\n+< ]"), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	var out bytes.Buffer
+	cmd := Cmd()
+	cmd.SetArgs([]string{a, b})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "+[->+<] move\n++[->+<]\n")
+
+	out.Reset()
+	cmd = Cmd()
+	cmd.SetArgs([]string{"-d", a, b})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "diff "+b+".orig "+b+"\n--- "+b+".orig\n+++ "+b+"\n"+
+		"@@ -1,2 +1,1 @@\n-++ [ ->\n-+< ]\n+++[->+<]\n")
+
+	out.Reset()
+	cmd = Cmd()
+	cmd.SetArgs([]string{"-w", "--width", "4", a, b})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "")
+	got, err := os.ReadFile(b)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.Equals, "++\n[\n    ->+<\n]\n")
+
+	err = os.WriteFile(b, []byte("]"), 0o600)
+	c.Assert(err, qt.IsNil)
+	cmd = Cmd()
+	cmd.SetArgs([]string{b})
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	err = cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, ".*b.bf: .*")
+}
@@ -0,0 +1,232 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// builder writes the DOT graph of a parsed source.
+type builder struct {
+	w     io.Writer
+	f     *syntax.File
+	prof  *bf.Profile // nil without execution counts
+	ids   int
+	edges []string
+}
+
+// item is a basic block, a run of commands other than brackets, or a loop.
+type item struct {
+	cmds []*syntax.Command
+	loop *syntax.Loop
+}
+
+// writeDOT writes the control-flow graph of f to w, named name. Basic blocks are boxes
+// labeled with their source range and their effects, and each loop is a cluster around
+// the test of its cell. Blocks and loops are annotated with the counts of p, if not nil.
+func writeDOT(w io.Writer, name string, f *syntax.File, p *bf.Profile) error {
+	g := &builder{w: w, f: f, prof: p}
+
+	fmt.Fprintf(w, "digraph %s {\n", quote(name))
+	fmt.Fprintln(w, `	node [shape=box, fontname="monospace"];`)
+	fmt.Fprintln(w, `	start [shape=oval];`)
+	fmt.Fprintln(w, `	end [shape=oval];`)
+	g.edge("start", g.list(f.Nodes, "end", "\t"), "")
+	for _, e := range g.edges {
+		fmt.Fprintf(w, "\t%s\n", e)
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func (g *builder) id(prefix string) string {
+	g.ids++
+	return fmt.Sprintf("%s%d", prefix, g.ids)
+}
+
+func (g *builder) edge(from, to, label string) {
+	if label == "" {
+		g.edges = append(g.edges, fmt.Sprintf("%s -> %s;", from, to))
+		return
+	}
+	g.edges = append(g.edges, fmt.Sprintf("%s -> %s [label=%s];", from, to, quote(label)))
+}
+
+// list writes the blocks and loops of nodes, which go on to next, and returns the first
+// of them, or next if there are none.
+func (g *builder) list(nodes []syntax.Node, next, indent string) string {
+	var items []item
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *syntax.Command:
+			if len(items) == 0 || items[len(items)-1].loop != nil {
+				items = append(items, item{})
+			}
+			items[len(items)-1].cmds = append(items[len(items)-1].cmds, n)
+		case *syntax.Loop:
+			items = append(items, item{loop: n})
+		}
+	}
+	if len(items) == 0 {
+		return next
+	}
+
+	ids := make([]string, len(items))
+	for i, it := range items {
+		if it.loop != nil {
+			ids[i] = g.id("loop")
+		} else {
+			ids[i] = g.id("block")
+		}
+	}
+	for i, it := range items {
+		succ := next
+		if i+1 < len(items) {
+			succ = ids[i+1]
+		}
+		if it.loop != nil {
+			g.loop(ids[i], it.loop, succ, indent)
+		} else {
+			g.block(ids[i], it.cmds, succ, indent)
+		}
+	}
+	return ids[0]
+}
+
+func (g *builder) block(id string, cmds []*syntax.Command, next, indent string) {
+	first, last := cmds[0].Offset, cmds[len(cmds)-1].Offset
+	lines := []string{g.f.Span(first, last)}
+	lines = append(lines, effects(cmds)...)
+	if g.prof != nil {
+		lines = append(lines, count(g.prof.Counts[first], "run", "runs"))
+	}
+
+	fmt.Fprintf(g.w, "%s%s [label=%s];\n", indent, id, leftLabel(lines))
+	g.edge(id, next, "")
+}
+
+func (g *builder) loop(id string, l *syntax.Loop, next, indent string) {
+	label := []string{"loop " + g.f.Span(l.Open, l.Close)}
+	switch d, ok := l.Net(); {
+	case !ok:
+		label = append(label, "pointer movement unknown")
+	case d == 0:
+		label = append(label, "pointer unchanged per iteration")
+	default:
+		label = append(label, fmt.Sprintf("pointer %+d per iteration", d))
+	}
+	if g.prof != nil {
+		if s, ok := g.prof.Loops[l.Open]; ok {
+			label = append(label, fmt.Sprintf("%s, %s", count(s.Entries, "entry", "entries"), count(s.Iterations, "iteration", "iterations")))
+		} else {
+			label = append(label, "never entered")
+		}
+	}
+
+	fmt.Fprintf(g.w, "%ssubgraph cluster_%s {\n", indent, id)
+	fmt.Fprintf(g.w, "%s\tlabel=%s;\n", indent, leftLabel(label))
+	fmt.Fprintf(g.w, "%s\tlabeljust=l;\n", indent)
+	fmt.Fprintf(g.w, "%s\t%s [shape=diamond, label=\"c[p] != 0\"];\n", indent, id)
+	body := g.list(l.Body, id, indent+"\t")
+	fmt.Fprintf(g.w, "%s}\n", indent)
+
+	g.edge(id, body, "yes")
+	g.edge(id, next, "no")
+}
+
+// effects describes what a block does, in order: the changes to the cells, grouped when
+// adjacent, the inputs and outputs, and then the net pointer movement, with the cells
+// relative to the pointer at the start of the block.
+func effects(cmds []*syntax.Command) []string {
+	type effect struct {
+		op  byte // '+', ',' or '.'
+		off int
+		n   int
+	}
+	var eff []effect
+	d := 0
+	for _, c := range cmds {
+		switch c.Op {
+		case '>':
+			d++
+		case '<':
+			d--
+		case '+', '-':
+			n := 1
+			if c.Op == '-' {
+				n = -1
+			}
+			if last := len(eff) - 1; last >= 0 && eff[last].op == '+' && eff[last].off == d {
+				eff[last].n += n
+				continue
+			}
+			eff = append(eff, effect{'+', d, n})
+		case ',', '.':
+			eff = append(eff, effect{c.Op, d, 0})
+		}
+	}
+
+	var lines []string
+	for _, e := range eff {
+		switch e.op {
+		case '+':
+			switch {
+			case e.n > 0:
+				lines = append(lines, fmt.Sprintf("%s += %d", cell(e.off), e.n))
+			case e.n < 0:
+				lines = append(lines, fmt.Sprintf("%s -= %d", cell(e.off), -e.n))
+			}
+		case ',':
+			lines = append(lines, "in "+cell(e.off))
+		case '.':
+			lines = append(lines, "out "+cell(e.off))
+		}
+	}
+	switch {
+	case d > 0:
+		lines = append(lines, fmt.Sprintf("p += %d", d))
+	case d < 0:
+		lines = append(lines, fmt.Sprintf("p -= %d", -d))
+	}
+	return lines
+}
+
+func cell(off int) string {
+	switch {
+	case off > 0:
+		return fmt.Sprintf("c[p+%d]", off)
+	case off < 0:
+		return fmt.Sprintf("c[p-%d]", -off)
+	}
+	return "c[p]"
+}
+
+func count(n int64, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// leftLabel returns a DOT label of left-justified lines.
+func leftLabel(lines []string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, l := range lines {
+		b.WriteString(escape(l))
+		b.WriteString(`\l`)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+func TestWriteDOT(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := syntax.Parse([]byte("+++[->++<]\n>[>]>[]."))
+	c.Assert(err, qt.IsNil)
+
+	var buf bytes.Buffer
+	err = writeDOT(&buf, `a "b".bf`, f, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `digraph "a \"b\".bf" {
+	node [shape=box, fontname="monospace"];
+	start [shape=oval];
+	end [shape=oval];
+	block1 [label="1:1-1:3\lc[p] += 3\l"];
+	subgraph cluster_loop2 {
+		label="loop 1:4-1:10\lpointer unchanged per iteration\l";
+		labeljust=l;
+		loop2 [shape=diamond, label="c[p] != 0"];
+		block8 [label="1:5-1:9\lc[p] -= 1\lc[p+1] += 2\l"];
+	}
+	block3 [label="2:1\lp += 1\l"];
+	subgraph cluster_loop4 {
+		label="loop 2:2-2:4\lpointer +1 per iteration\l";
+		labeljust=l;
+		loop4 [shape=diamond, label="c[p] != 0"];
+		block9 [label="2:3\lp += 1\l"];
+	}
+	block5 [label="2:5\lp += 1\l"];
+	subgraph cluster_loop6 {
+		label="loop 2:6-2:7\lpointer unchanged per iteration\l";
+		labeljust=l;
+		loop6 [shape=diamond, label="c[p] != 0"];
+	}
+	block7 [label="2:8\lout c[p]\l"];
+	block1 -> loop2;
+	block8 -> loop2;
+	loop2 -> block8 [label="yes"];
+	loop2 -> block3 [label="no"];
+	block3 -> loop4;
+	block9 -> loop4;
+	loop4 -> block9 [label="yes"];
+	loop4 -> block5 [label="no"];
+	block5 -> loop6;
+	loop6 -> loop6 [label="yes"];
+	loop6 -> block7 [label="no"];
+	block7 -> end;
+	start -> block1;
+}
+`)
+}
+
+func TestEffects(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := syntax.Parse([]byte("++-<,>>+<+.>>"))
+	c.Assert(err, qt.IsNil)
+	var cmds []*syntax.Command
+	for _, n := range f.Nodes {
+		cmds = append(cmds, n.(*syntax.Command))
+	}
+	c.Assert(effects(cmds), qt.DeepEquals, []string{
+		"c[p] += 1", "in c[p-1]", "c[p+1] += 1", "c[p] += 1", "out c[p]", "p += 2",
+	})
+}
@@ -0,0 +1,95 @@
+// Package graph provides functionality for the `graph` command of the CLI
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// options holds the flags of the graph command
+type options struct {
+	profile bool
+	input   string
+}
+
+// Cmd is the command for drawing the control-flow graph of BF programs
+func Cmd() *cobra.Command {
+	var o options
+
+	cmd := &cobra.Command{
+		Use:   "graph [--profile [-i input_file]] file_path",
+		Args:  cobra.ExactArgs(1),
+		Short: "Writes the control-flow graph of a BF program in Graphviz DOT",
+		Long: `Writes the basic blocks and the loops of a BF program as a Graphviz DOT graph. Blocks are
+labeled with their source range, their changes to the cells, their inputs and outputs, and
+their net pointer movement, relative to the pointer at the start of the block. Loops are
+labeled with their pointer movement per iteration. With --profile, the program is run first
+and the graph shows how many times each block ran and each loop iterated.
+
+	bf graph prog.bf | dot -Tsvg > prog.svg`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return graph(args[0], o, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.profile,
+		"profile", false, "run the program and annotate the graph with execution counts")
+
+	cmd.Flags().StringVarP(&o.input,
+		"input", "i", "", "file to read the program inputs (, command) from, with --profile (default is stdin)")
+
+	return cmd
+}
+
+// graph writes the graph of the program in file to w
+func graph(file string, o options, w io.Writer) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	f, err := syntax.Parse(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	var p *bf.Profile
+	if o.profile {
+		p, err = profile(src, o.input)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeDOT(w, file, f, p)
+}
+
+// profile runs src, discarding its output, and returns its profile
+func profile(src []byte, input string) (*bf.Profile, error) {
+	var in io.Reader = os.Stdin
+	if input != "" {
+		inp, err := os.ReadFile(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+		in = bytes.NewReader(inp)
+	}
+
+	var p bf.Profile
+	bfi, err := bf.New(bytes.NewReader(src), io.Discard, in, bf.WithProfile(&p))
+	if err != nil {
+		return nil, err
+	}
+	// unlike Exec, Continue reports where the program failed
+	_, err = bfi.Continue()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to run the program: %w", err)
+	}
+	return &p, nil
+}
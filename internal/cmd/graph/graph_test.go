@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "prog.bf")
+	err := os.WriteFile(prog, []byte(",[->+<]>[-]"), 0o600)
+	c.Assert(err, qt.IsNil)
+	input := filepath.Join(dir, "input")
+	err = os.WriteFile(input, []byte("3\n"), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	var out bytes.Buffer
+	cmd := Cmd()
+	cmd.SetArgs([]string{"--profile", "-i", input, prog})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+
+	dot := out.String()
+	c.Assert(dot, qt.Contains, `block1 [label="1:1\lin c[p]\l1 run\l"];`)
+	c.Assert(dot, qt.Contains, `label="loop 1:2-1:7\lpointer unchanged per iteration\l1 entry, 3 iterations\l";`)
+	c.Assert(dot, qt.Contains, `label="1:3-1:6\lc[p] -= 1\lc[p+1] += 1\l3 runs\l"];`)
+
+	bad := filepath.Join(dir, "bad.bf")
+	err = os.WriteFile(bad, []byte("[["), 0o600)
+	c.Assert(err, qt.IsNil)
+	cmd = Cmd()
+	cmd.SetArgs([]string{bad})
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	err = cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, ".*bad.bf: loop openings/closing .*")
+}
@@ -0,0 +1,74 @@
+// Package lint provides functionality for the `lint` command of the CLI
+package lint
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf/lint"
+)
+
+// Cmd is the command for finding likely mistakes in BF sources
+func Cmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "lint [--format text|json|sarif] file_path...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Finds likely mistakes in BF sources",
+		Long: `Checks BF sources for loops that never run or never end, commands that cancel each
+other out, loops that move the pointer on each iteration, pointer moves left of the first
+cell, and command characters in the text of comments. It fails if it finds errors or warnings.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(args, format, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&format,
+		"format", "text", "output format: text, json or sarif")
+
+	return cmd
+}
+
+// run lints the files and writes the diagnostics to w in format.
+func run(files []string, format string, w io.Writer) error {
+	write := map[string]func(io.Writer, []lint.Diagnostic) error{
+		"text":  lint.WriteText,
+		"json":  lint.WriteJSON,
+		"sarif": lint.WriteSARIF,
+	}[format]
+	if write == nil {
+		return fmt.Errorf("unknown format %q: want text, json or sarif", format)
+	}
+
+	var diags []lint.Diagnostic
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		diags = append(diags, lint.Lint(file, src)...)
+	}
+
+	err := write(w, diags)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, d := range diags {
+		if d.Severity != lint.Note {
+			problems++
+		}
+	}
+	if problems == 1 {
+		return fmt.Errorf("1 problem found")
+	}
+	if problems > 1 {
+		return fmt.Errorf("%d problems found", problems)
+	}
+	return nil
+}
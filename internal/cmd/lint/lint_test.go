@@ -0,0 +1,54 @@
+package lint
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0o600)
+		c.Assert(err, qt.IsNil)
+		return path
+	}
+	clean := write("clean.bf", "++[>+<-]>.")
+	note := write("note.bf", ",[>,]")
+	bad := write("bad.bf", "+[-]\n[.]")
+
+	execute := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := Cmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := execute(clean, note)
+	c.Assert(err, qt.IsNil)
+	c.Assert(out, qt.Equals, note+":1:2: note: the loop moves the pointer by +1 on each iteration (unbalanced-loop)\n")
+
+	out, err = execute(bad)
+	c.Assert(err, qt.ErrorMatches, "1 problem found")
+	c.Assert(out, qt.Equals, bad+":2:1: warning: the loop never runs: the current cell is always zero here (dead-loop)\n")
+
+	out, err = execute("--format", "json", clean)
+	c.Assert(err, qt.IsNil)
+	c.Assert(out, qt.Equals, "[]\n")
+
+	out, err = execute("--format", "sarif", bad)
+	c.Assert(err, qt.ErrorMatches, "1 problem found")
+	c.Assert(out, qt.Contains, `"ruleId": "dead-loop"`)
+
+	_, err = execute("--format", "xml", clean)
+	c.Assert(err, qt.ErrorMatches, `unknown format "xml": want text, json or sarif`)
+}
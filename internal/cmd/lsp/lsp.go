@@ -0,0 +1,21 @@
+// Package lsp provides functionality for the `lsp` command of the CLI
+package lsp
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf/internal/lsp"
+)
+
+// Cmd is the command for serving the Language Server Protocol
+func Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Args:  cobra.ExactArgs(0),
+		Short: "Runs a Language Server Protocol server over stdio",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lsp.Serve(cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+
+	return cmd
+}
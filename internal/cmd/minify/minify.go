@@ -0,0 +1,157 @@
+// Package minify provides functionality for the `minify` command of the CLI
+package minify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/equiv"
+	"github.com/thesoulless/bf/internal/syntax"
+	"github.com/thesoulless/bf/minify"
+	"github.com/thesoulless/bf/sourcemap"
+)
+
+// options holds the flags of the minify command
+type options struct {
+	output    string
+	sourceMap string
+	check     equiv.Config
+}
+
+// Cmd is the command for minifying BF programs
+func Cmd() *cobra.Command {
+	var o options
+
+	cmd := &cobra.Command{
+		Use:   "minify [-o output_file] [--source-map map_file] file_path",
+		Args:  cobra.ExactArgs(1),
+		Short: "Makes a BF program smaller without changing what it does",
+		Long: `Drops the comments of a BF program, the commands that cancel each other out, and the
+loops that never run. The minified program is then checked to behave like the original on
+every input of up to --inputs values between --min and --max, as bf equiv does, and the
+command fails if it does not.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(args[0], o, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.output,
+		"output", "o", "", "file to write the minified program to (default is stdout)")
+
+	cmd.Flags().StringVar(&o.sourceMap,
+		"source-map", "", "file to write a source map back to the original program to")
+
+	cmd.Flags().IntVar(&o.check.Inputs,
+		"inputs", 1, "most input values given to a run when checking the result")
+
+	cmd.Flags().Int32Var(&o.check.Min,
+		"min", 0, "smallest input value when checking the result")
+
+	cmd.Flags().Int32Var(&o.check.Max,
+		"max", 255, "largest input value when checking the result")
+
+	cmd.Flags().Int64Var(&o.check.Steps,
+		"steps", equiv.DefaultSteps, "most commands run by a program on an input when checking the result")
+
+	return cmd
+}
+
+// run minifies the program in file, checks it, and writes it and its source map
+func run(file string, o options, stdout, stderr io.Writer) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	_, err = bf.New(bytes.NewReader(src), io.Discard, strings.NewReader(""))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	r, err := minify.Minify(src)
+	if errors.Is(err, minify.ErrNoOp) {
+		return fmt.Errorf("%s: %w, so there is no program to write", file, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	res, err := equiv.Check(src, r.Src, o.check)
+	if err != nil {
+		return fmt.Errorf("failed to check the minified program: %w", err)
+	}
+	if d := res.Diff; d != nil {
+		return fmt.Errorf("the minified program behaves differently on the input %v: %s, instead of %s",
+			d.Input, d.B, d.A)
+	}
+	if res.Incomplete > 0 {
+		fmt.Fprintf(stderr, "warning: %d inputs ran out of steps, the minified program may behave differently on them\n",
+			res.Incomplete)
+	}
+
+	if o.output == "" {
+		_, err = stdout.Write(r.Src)
+	} else {
+		err = os.WriteFile(o.output, r.Src, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the minified program: %w", err)
+	}
+
+	if o.sourceMap != "" {
+		return writeSourceMap(o.sourceMap, file, o.output, src, r)
+	}
+	return nil
+}
+
+// writeSourceMap writes the source map from the minified program, on a single line, to
+// the original program in file. The paths in the map are relative to its directory.
+func writeSourceMap(path, file, output string, src []byte, r *minify.Result) error {
+	f, _ := syntax.Parse(src)
+	mappings := make([]sourcemap.Mapping, len(r.Offsets))
+	for i, off := range r.Offsets {
+		line, col := f.Position(off)
+		mappings[i] = sourcemap.Mapping{Line: 1, Column: i + 1, SourceLine: line, SourceColumn: col}
+	}
+	dir := filepath.Dir(path)
+	if output != "" {
+		output = rel(dir, output)
+	}
+	m := sourcemap.New(output, []string{rel(dir, file)}, mappings)
+
+	var b strings.Builder
+	err := json.NewEncoder(&b).Encode(m)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(path, []byte(b.String()), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write the source map: %w", err)
+	}
+	return nil
+}
+
+// rel returns path relative to dir, or path if it can not be made relative
+func rel(dir, path string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	r, err := filepath.Rel(absDir, abs)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(r)
+}
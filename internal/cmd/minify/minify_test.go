@@ -0,0 +1,74 @@
+package minify
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "prog.bf")
+	err := os.WriteFile(prog, []byte("[comment]\n,+-[->+<]\n>."), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	var out bytes.Buffer
+	cmd := Cmd()
+	cmd.SetArgs([]string{prog})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, ",[->+<]>.")
+
+	minFile := filepath.Join(dir, "prog.min.bf")
+	smap := filepath.Join(dir, "prog.min.bf.map")
+	out.Reset()
+	cmd = Cmd()
+	cmd.SetArgs([]string{"-o", minFile, "--source-map", smap, prog})
+	cmd.SetOut(&out)
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+	c.Assert(out.String(), qt.Equals, "")
+
+	got, err := os.ReadFile(minFile)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.Equals, ",[->+<]>.")
+	got, err = os.ReadFile(smap)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(got), qt.Equals, `{"version":3,"file":"prog.min.bf","sources":["prog.bf"],`+
+		`"names":[],"mappings":"AACA,CAAG,CAAC,CAAC,CAAC,CAAC,CAAC,CACR,CAAC"}`+"\n")
+}
+
+func TestCmd_Errors(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "prog.bf")
+	err := os.WriteFile(prog, []byte("+[<"), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	cmd := Cmd()
+	cmd.SetArgs([]string{prog})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err = cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, `.*prog.bf: loop openings/closing \(\[/\]\) count does not match`)
+
+	// nothing is left to write
+	out := filepath.Join(dir, "prog.min.bf")
+	err = os.WriteFile(prog, []byte("+-[.] does nothing"), 0o600)
+	c.Assert(err, qt.IsNil)
+	cmd = Cmd()
+	cmd.SetArgs([]string{"-o", out, prog})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	err = cmd.Execute()
+	c.Assert(err, qt.ErrorMatches, `.*prog.bf: the program does nothing, so there is no program to write`)
+	_, err = os.Stat(out)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+}
@@ -0,0 +1,96 @@
+// Package profile provides functionality for the `profile` command of the CLI
+package profile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+)
+
+// options holds the flags of the profile command
+type options struct {
+	html  string
+	input string
+}
+
+// Cmd is the command for profiling BF programs
+func Cmd() *cobra.Command {
+	var o options
+
+	cmd := &cobra.Command{
+		Use:   "profile [--html out.html] [-i input_file] file_path",
+		Args:  cobra.ExactArgs(1),
+		Short: "Profiles a BF program, reporting its hot loops or writing an HTML heat map",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return profile(args[0], o, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		},
+	}
+
+	cmd.Flags().StringVar(&o.html,
+		"html", "", "write an HTML page of the source colored by execution count, instead of the report")
+
+	cmd.Flags().StringVarP(&o.input,
+		"input", "i", "", "file to read the program inputs (, command) from (default is stdin)")
+
+	return cmd
+}
+
+// profile runs the program in file, writing its output to out, and then the report of
+// its hot loops to report unless an HTML page is requested.
+func profile(file string, o options, out, report io.Writer) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var input io.Reader = os.Stdin
+	if o.input != "" {
+		inp, err := os.ReadFile(o.input)
+		if err != nil {
+			return fmt.Errorf("failed to read input file: %w", err)
+		}
+		input = bytes.NewReader(inp)
+	}
+
+	var p bf.Profile
+	bfi, err := bf.New(bytes.NewReader(src), out, input, bf.WithProfile(&p))
+	if err != nil {
+		return err
+	}
+	// unlike Exec, Continue reports where the program failed
+	_, err = bfi.Continue()
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if err != nil {
+		// still show where the program spent its time until the error
+		fmt.Fprintf(report, "error: %v\n", err)
+	}
+
+	if o.html == "" {
+		rerr := p.Report(report, 0)
+		if err == nil {
+			err = rerr
+		}
+		return err
+	}
+
+	f, ferr := os.Create(o.html)
+	if ferr != nil {
+		return fmt.Errorf("failed to create the HTML file: %w", ferr)
+	}
+	herr := p.WriteHTML(f, file)
+	if cerr := f.Close(); herr == nil {
+		herr = cerr
+	}
+	if err == nil {
+		err = herr
+	}
+
+	return err
+}
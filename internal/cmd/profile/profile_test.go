@@ -0,0 +1,53 @@
+package profile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "prog.bf")
+	err := os.WriteFile(prog, []byte(",[>+.<-]"), 0o600)
+	c.Assert(err, qt.IsNil)
+	input := filepath.Join(dir, "input")
+	err = os.WriteFile(input, []byte("3\n"), 0o600)
+	c.Assert(err, qt.IsNil)
+
+	execute := func(args ...string) (string, string) {
+		var out, report bytes.Buffer
+		cmd := Cmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		cmd.SetErr(&report)
+		err := cmd.Execute()
+		c.Assert(err, qt.IsNil)
+		return out.String(), report.String()
+	}
+
+	t.Run("report", func(t *testing.T) {
+		out, report := execute("-i", input, prog)
+		c.Assert(out, qt.Equals, "\x01\x02\x03")
+
+		lines := strings.Split(report, "\n")
+		c.Assert(strings.HasPrefix(lines[0], "20 steps in "), qt.IsTrue)
+		c.Assert(strings.Fields(lines[3])[:5], qt.DeepEquals, []string{"1:2", "19", "95.0%", "1", "3"})
+	})
+
+	t.Run("html", func(t *testing.T) {
+		page := filepath.Join(dir, "prog.html")
+		_, report := execute("--html", page, "-i", input, prog)
+		c.Assert(report, qt.Equals, "")
+
+		b, err := os.ReadFile(page)
+		c.Assert(err, qt.IsNil)
+		c.Assert(string(b), qt.Contains, `<span class="h8" title="3 runs">&gt;&#43;.&lt;-</span>`)
+	})
+}
@@ -2,28 +2,54 @@
 package run
 
 import (
-	"bytes"
+	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/sourcemap"
 )
 
+// profileLoops is the number of loops reported by --profile
+const profileLoops = 10
+
+// options holds the flags of the run command
+type options struct {
+	debugHash bool
+	watches   []string
+
+	trace      string // trace file path
+	traceEvery int64
+	traceSteps string // A..B
+	traceLines string // A..B
+
+	profile bool
+	pprof   string // pprof file path
+
+	stats       bool
+	detectLoops bool
+
+	sourceMap string // source map file path
+}
+
 // Cmd is the command for running the BF commands
 func Cmd() *cobra.Command {
 	var file string
 	var s string
+	var o options
 
 	cmd := &cobra.Command{
 		Use:   "run [-s \"bf commands\"] | [-f file_path]",
 		Args:  cobra.ExactArgs(0),
 		Short: "Runs BF commands",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return run(s, file)
+			return run(s, file, o)
 		},
 	}
 
@@ -33,61 +59,286 @@ func Cmd() *cobra.Command {
 	cmd.Flags().StringVarP(&s,
 		"string", "s", "", "BF commands")
 
+	cmd.Flags().BoolVar(&o.debugHash,
+		"debug-hash", false, "treat '#' as a command that dumps the pointer and nearby cells to stderr")
+
+	cmd.Flags().StringArrayVar(&o.watches,
+		"watch", nil, "stop when a watchpoint triggers, e.g. 'cell[5]==0' or 'ptr in 10..20' (repeatable)")
+
+	cmd.Flags().StringVar(&o.trace,
+		"trace", "", "write each command run to a JSON Lines file")
+
+	cmd.Flags().Int64Var(&o.traceEvery,
+		"trace-every", 0, "trace one command out of N")
+
+	cmd.Flags().StringVar(&o.traceSteps,
+		"trace-steps", "", "trace the steps in the inclusive range A..B, either end can be left out")
+
+	cmd.Flags().StringVar(&o.traceLines,
+		"trace-lines", "", "trace the commands on the lines in the inclusive range A..B, either end can be left out")
+
+	cmd.Flags().BoolVar(&o.profile,
+		"profile", false, "report the hot loops on stderr")
+
+	cmd.Flags().StringVar(&o.pprof,
+		"pprof", "", "write a pprof profile of the commands run, for go tool pprof")
+
+	cmd.Flags().BoolVar(&o.stats,
+		"stats", false, "report the execution statistics on stderr")
+
+	cmd.Flags().BoolVar(&o.detectLoops,
+		"detect-loops", false, "stop with an error when a loop provably never ends")
+
+	cmd.Flags().StringVar(&o.sourceMap,
+		"source-map", "", "source map file, to report the positions in the original sources")
+
 	return cmd
 }
 
 // run reads bf commands either from string or a file, and
 // uses the underlying bf library to execute them
-func run(s string, file string) error {
+func run(s string, file string, o options) error {
 	if s != "" {
-		return runString(s)
+		return runString(s, o)
+	}
+
+	return runFile(file, o)
+}
+
+func runString(s string, o options) error {
+	return execute(strings.NewReader(s), "<string>", o)
+}
+
+func runFile(file string, o options) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
 	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Printf("error closing file: %v", err)
+		}
+	}(f)
 
-	return runFile(file)
+	return execute(f, file, o)
 }
 
-func runString(s string) error {
-	bfi, err := bf.New(strings.NewReader(s), os.Stdout, os.Stdin)
+// execute runs the commands read from src, printing the execution errors. name is the
+// file name of src.
+func execute(src io.Reader, name string, o options) error {
+	var opts []bf.Option
+	if o.debugHash {
+		opts = append(opts, bf.WithDebugHash(os.Stderr))
+	}
+
+	if o.trace != "" {
+		filter, err := traceFilter(o)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Create(o.trace)
+		if err != nil {
+			return fmt.Errorf("failed to create the trace file: %w", err)
+		}
+		w := bufio.NewWriter(f)
+		defer func() {
+			err := w.Flush()
+			if err == nil {
+				err = f.Close()
+			}
+			if err != nil {
+				log.Printf("error writing the trace file: %v", err)
+			}
+		}()
+		opts = append(opts, bf.WithTrace(w, filter))
+	}
+
+	if o.detectLoops {
+		opts = append(opts, bf.WithLoopDetection())
+	}
+
+	if o.sourceMap != "" {
+		m, err := sourcemap.ReadFile(o.sourceMap)
+		if err != nil {
+			return fmt.Errorf("failed to read the source map: %w", err)
+		}
+		opts = append(opts, bf.WithSourceMap(m))
+	}
+
+	var profile *bf.Profile
+	if o.profile || o.pprof != "" {
+		profile = &bf.Profile{}
+		opts = append(opts, bf.WithProfile(profile))
+	}
+
+	var watches []bf.Watchpoint
+	for _, expr := range o.watches {
+		w, err := bf.ParseWatchpoint(expr)
+		if err != nil {
+			return err
+		}
+		watches = append(watches, w)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	bfi, err := bf.New(src, out, &flushReader{r: os.Stdin, out: out}, opts...)
 	if err != nil {
 		return err
 	}
 
-	err = bfi.Exec()
+	if len(watches) == 0 {
+		// unlike Exec, Continue reports where the program failed
+		_, err = bfi.Continue()
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	} else {
+		err = watch(bfi, watches, out)
+	}
+	if ferr := out.Flush(); err == nil && ferr != nil {
+		err = fmt.Errorf("failed writing to the output: %w", ferr)
+	}
 
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 	}
 
+	if o.stats {
+		serr := bfi.Stats().Report(os.Stderr)
+		if err == nil {
+			err = serr
+		}
+	}
+
+	if profile != nil {
+		perr := writeProfile(profile, name, o)
+		if err == nil {
+			err = perr
+		}
+	}
+
 	return err
 }
 
-func runFile(file string) error {
-	f, err := os.Open(file)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer func(f *os.File) {
-		err := f.Close()
+// writeProfile reports the hot loops and writes the pprof profile, as requested by o
+func writeProfile(p *bf.Profile, name string, o options) error {
+	if o.profile {
+		err := p.Report(os.Stderr, profileLoops)
 		if err != nil {
-			log.Printf("error closing file: %v", err)
+			return err
 		}
-	}(f)
+	}
+	if o.pprof == "" {
+		return nil
+	}
 
-	fb, err := io.ReadAll(f)
+	f, err := os.Create(o.pprof)
 	if err != nil {
-		return fmt.Errorf("faild to read file: %w", err)
+		return fmt.Errorf("failed to create the profile file: %w", err)
+	}
+	err = p.WritePprof(f, name)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// watch runs bfi until the first watchpoint triggers, and reports it on stderr, after
+// flushing the output written so far
+func watch(bfi *bf.BF, watches []bf.Watchpoint, out *bufio.Writer) error {
+	for _, w := range watches {
+		bfi.AddWatchpoint(w)
 	}
 
-	bfi, err := bf.New(bytes.NewReader(fb), os.Stdout, os.Stdin)
+	_, err := bfi.Continue()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	err = out.Flush()
+	if err != nil {
+		return fmt.Errorf("failed writing to the output: %w", err)
+	}
+
+	for _, h := range bfi.WatchHits() {
+		fmt.Fprintf(os.Stderr, "watchpoint %s\n", h)
+	}
+	fmt.Fprintf(os.Stderr, "stopped with ptr=%d", bfi.Pointer())
+	if loops := bfi.LoopStack(); len(loops) > 0 {
+		fmt.Fprint(os.Stderr, ", in loops:")
+		for _, l := range loops {
+			fmt.Fprintf(os.Stderr, " %s", l)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
 
-	err = bfi.Exec()
+	return nil
+}
 
+// flushReader flushes the output before each read of the input, so the prompts written
+// before a ',' are shown before it waits for the input.
+type flushReader struct {
+	r   io.Reader
+	out *bufio.Writer
+}
+
+func (f *flushReader) Read(p []byte) (int, error) {
+	err := f.out.Flush()
 	if err != nil {
-		fmt.Printf("error: %v\n", err)
+		return 0, err
+	}
+	return f.r.Read(p)
+}
+
+// traceFilter builds the trace filter from the trace flags
+func traceFilter(o options) (bf.TraceFilter, error) {
+	filter := bf.TraceFilter{Every: o.traceEvery}
+
+	var err error
+	filter.FromStep, filter.ToStep, err = parseRange(o.traceSteps)
+	if err != nil {
+		return filter, fmt.Errorf("invalid --trace-steps: %w", err)
 	}
 
-	return err
+	from, to, err := parseRange(o.traceLines)
+	if err != nil {
+		return filter, fmt.Errorf("invalid --trace-lines: %w", err)
+	}
+	filter.FromLine, filter.ToLine = int(from), int(to)
+
+	return filter, nil
+}
+
+// parseRange parses an inclusive A..B range, where a missing end is returned as zero
+func parseRange(s string) (from, to int64, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+
+	a, b, ok := strings.Cut(s, "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q: want A..B", s)
+	}
+	if a != "" {
+		from, err = strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%q: %w", s, err)
+		}
+	}
+	if b != "" {
+		to, err = strconv.ParseInt(b, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%q: %w", s, err)
+		}
+	}
+	if from < 0 || to < 0 || (to > 0 && to < from) {
+		return 0, 0, fmt.Errorf("%q: invalid range", s)
+	}
+
+	return from, to, nil
 }
@@ -1,8 +1,11 @@
 package run
 
 import (
+	"bufio"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
@@ -61,3 +64,206 @@ func TestCmd(t *testing.T) {
 		c.Assert(string(out), qt.Equals, want)
 	})
 }
+
+func TestFlushReader(t *testing.T) {
+	c := qt.New(t)
+
+	var b strings.Builder
+	out := bufio.NewWriter(&b)
+	r := &flushReader{r: strings.NewReader("5\n"), out: out}
+
+	_, err := out.WriteString("number? ")
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.String(), qt.Equals, "")
+
+	in, err := ioutil.ReadAll(r)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(in), qt.Equals, "5\n")
+	c.Assert(b.String(), qt.Equals, "number? ")
+}
+
+func TestCmd_DebugHash(t *testing.T) {
+	c := qt.New(t)
+
+	cmd := Cmd()
+	err := cmd.Flags().Set("string", "++>+++#")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("debug-hash", "true")
+	c.Assert(err, qt.IsNil)
+	cmd.Args = nil
+
+	oStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	err = cmd.Execute()
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stderr = oStderr
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(out), qt.Equals, "#1:7 ptr=1 cells[0..5]: 2 [3] 0 0 0 0\n")
+}
+
+func TestCmd_Watch(t *testing.T) {
+	c := qt.New(t)
+
+	cmd := Cmd()
+	err := cmd.Flags().Set("string", "+++[>++<-]>[-]")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("watch", "cell[1]==4")
+	c.Assert(err, qt.IsNil)
+	cmd.Args = nil
+
+	oStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	err = cmd.Execute()
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stderr = oStderr
+
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(out), qt.Equals, "watchpoint cell[1]==4 at 1:7: 3 -> 4\nstopped with ptr=1, in loops: 1:4\n")
+}
+
+func TestCmd_Trace(t *testing.T) {
+	c := qt.New(t)
+
+	trace := filepath.Join(t.TempDir(), "trace.jsonl")
+	cmd := Cmd()
+	err := cmd.Flags().Set("string", "++[>+<-]")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("trace", trace)
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("trace-steps", "3..5")
+	c.Assert(err, qt.IsNil)
+	cmd.Args = nil
+
+	err = cmd.Execute()
+	c.Assert(err, qt.IsNil)
+
+	b, err := os.ReadFile(trace)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(b), qt.Equals, `{"step":3,"offset":2,"line":1,"col":3,"op":"[","ptr":0,"cell":2}
+{"step":4,"offset":3,"line":1,"col":4,"op":">","ptr":1,"cell":0}
+{"step":5,"offset":4,"line":1,"col":5,"op":"+","ptr":1,"cell":1}
+`)
+}
+
+func TestParseRange(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		s        string
+		from, to int64
+		err      string
+	}{
+		{"", 0, 0, ""},
+		{"3..7", 3, 7, ""},
+		{"10..", 10, 0, ""},
+		{"..4", 0, 4, ""},
+		{"7..3", 0, 0, `"7..3": invalid range`},
+		{"5", 0, 0, `"5": want A..B`},
+		{"a..b", 0, 0, `"a..b": .*invalid syntax`},
+	}
+	for _, tt := range tests {
+		from, to, err := parseRange(tt.s)
+		if tt.err != "" {
+			c.Assert(err, qt.ErrorMatches, tt.err)
+			continue
+		}
+		c.Assert(err, qt.IsNil)
+		c.Assert([]int64{from, to}, qt.DeepEquals, []int64{tt.from, tt.to})
+	}
+}
+
+func TestCmd_Profile(t *testing.T) {
+	c := qt.New(t)
+
+	pprof := filepath.Join(t.TempDir(), "prof.pb.gz")
+	cmd := Cmd()
+	err := cmd.Flags().Set("string", "++[>+++[-]<-]")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("profile", "true")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("pprof", pprof)
+	c.Assert(err, qt.IsNil)
+	cmd.Args = nil
+
+	oStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	err = cmd.Execute()
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stderr = oStderr
+
+	c.Assert(err, qt.IsNil)
+	lines := strings.Split(string(out), "\n")
+	c.Assert(strings.HasPrefix(lines[0], "31 steps in "), qt.IsTrue)
+	c.Assert(strings.Fields(lines[3])[:5], qt.DeepEquals, []string{"1:3", "29", "93.5%", "1", "2"})
+	c.Assert(strings.Fields(lines[4])[:5], qt.DeepEquals, []string{"1:8", "14", "45.2%", "2", "6"})
+
+	info, err := os.Stat(pprof)
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.Size() > 0, qt.IsTrue)
+}
+
+func TestCmd_Stats(t *testing.T) {
+	c := qt.New(t)
+
+	cmd := Cmd()
+	err := cmd.Flags().Set("string", "++[>+++[-]<-]")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("stats", "true")
+	c.Assert(err, qt.IsNil)
+	cmd.Args = nil
+
+	oStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	err = cmd.Execute()
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stderr = oStderr
+
+	c.Assert(err, qt.IsNil)
+	lines := strings.Split(string(out), "\n")
+	c.Assert(strings.Fields(lines[0]), qt.DeepEquals, []string{"steps", "31"})
+	c.Assert(string(out), qt.Contains, "max loop depth  2\n")
+	c.Assert(string(out), qt.Contains, "wall time")
+}
+
+func TestCmd_DetectLoops(t *testing.T) {
+	c := qt.New(t)
+
+	cmd := Cmd()
+	err := cmd.Flags().Set("string", "+[>+[-]<]")
+	c.Assert(err, qt.IsNil)
+	err = cmd.Flags().Set("detect-loops", "true")
+	c.Assert(err, qt.IsNil)
+	cmd.Args = nil
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	oStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err = cmd.Execute()
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = oStdout
+
+	c.Assert(err, qt.ErrorMatches, "infinite loop at 1:2 \\(line:column\\)")
+	c.Assert(string(out), qt.Equals, "error: infinite loop at 1:2 (line:column)\n")
+}
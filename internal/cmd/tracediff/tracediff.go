@@ -0,0 +1,139 @@
+// Package tracediff provides functionality for the `tracediff` command of the CLI
+package tracediff
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thesoulless/bf"
+)
+
+// options holds the flags of the tracediff command
+type options struct {
+	run     bool
+	input   string
+	context int
+}
+
+// Cmd is the command for finding the first divergence between two runs
+func Cmd() *cobra.Command {
+	var o options
+
+	cmd := &cobra.Command{
+		Use:   "tracediff a.jsonl b.jsonl | --run a.bf b.bf [-i input_file]",
+		Args:  cobra.ExactArgs(2),
+		Short: "Finds the first step where two runs differ in pointer, cell value or output",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				div *bf.Divergence
+				err error
+			)
+			if o.run {
+				div, err = compareRuns(args[0], args[1], o)
+			} else {
+				div, err = diffTraces(args[0], args[1], o)
+			}
+			if err != nil {
+				return err
+			}
+
+			report(cmd.OutOrStdout(), div)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&o.run,
+		"run", false, "run the two BF files side by side instead of reading traces")
+
+	cmd.Flags().StringVarP(&o.input,
+		"input", "i", "", "file to read the program inputs (, command) from, with --run")
+
+	cmd.Flags().IntVarP(&o.context,
+		"context", "C", 5, "number of steps shown before the divergence")
+
+	return cmd
+}
+
+func diffTraces(a, b string, o options) (*bf.Divergence, error) {
+	fa, err := os.Open(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer closeFile(fa)
+
+	fb, err := os.Open(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer closeFile(fb)
+
+	return bf.DiffTraces(fa, fb, o.context)
+}
+
+func compareRuns(a, b string, o options) (*bf.Divergence, error) {
+	var inp []byte
+	if o.input != "" {
+		var err error
+		inp, err = os.ReadFile(o.input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %w", err)
+		}
+	}
+
+	var runs [2]*bf.BF
+	for i, file := range []string{a, b} {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		runs[i], err = bf.New(bytes.NewReader(src), io.Discard, bytes.NewReader(inp))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	return bf.CompareRuns(runs[0], runs[1], o.context)
+}
+
+// report writes the divergence with its context
+func report(w io.Writer, div *bf.Divergence) {
+	if div == nil {
+		fmt.Fprintln(w, "no divergence")
+		return
+	}
+
+	fmt.Fprintln(w, div)
+	fmt.Fprintf(w, "  a: %s\n", event(div.A, div.ErrA))
+	fmt.Fprintf(w, "  b: %s\n", event(div.B, div.ErrB))
+
+	if len(div.ContextA) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "context:")
+	for i := range div.ContextA {
+		fmt.Fprintf(w, "  a: %s\n", div.ContextA[i])
+		fmt.Fprintf(w, "  b: %s\n", div.ContextB[i])
+	}
+}
+
+func event(e *bf.TraceEvent, err error) string {
+	if e == nil {
+		return "ended"
+	}
+	if err != nil {
+		return fmt.Sprintf("%s, failed", e)
+	}
+	return e.String()
+}
+
+func closeFile(f *os.File) {
+	err := f.Close()
+	if err != nil {
+		log.Printf("error closing file: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package tracediff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCmd(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0o600)
+		c.Assert(err, qt.IsNil)
+		return path
+	}
+
+	execute := func(args ...string) string {
+		var out bytes.Buffer
+		cmd := Cmd()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		err := cmd.Execute()
+		c.Assert(err, qt.IsNil)
+		return out.String()
+	}
+
+	t.Run("traces", func(t *testing.T) {
+		a := write("a.jsonl", `{"step":1,"offset":0,"line":1,"col":1,"op":"+","ptr":0,"cell":1}
+{"step":2,"offset":1,"line":1,"col":2,"op":"+","ptr":0,"cell":2}
+`)
+		b := write("b.jsonl", `{"step":1,"offset":0,"line":1,"col":1,"op":"+","ptr":0,"cell":1}
+{"step":2,"offset":1,"line":1,"col":2,"op":"-","ptr":0,"cell":0}
+`)
+		c.Assert(execute(a, b), qt.Equals, `runs diverge at step 2: cell differs
+  a: step 2 at 1:2: + ptr=0 cell=2
+  b: step 2 at 1:2: - ptr=0 cell=0
+context:
+  a: step 1 at 1:1: + ptr=0 cell=1
+  b: step 1 at 1:1: + ptr=0 cell=1
+`)
+		c.Assert(execute(a, a), qt.Equals, "no divergence\n")
+	})
+
+	t.Run("run", func(t *testing.T) {
+		a := write("a.bf", ",[->+<]>.")
+		b := write("b.bf", ",[->++<]>.")
+		input := write("input", "2\n")
+		c.Assert(execute("--run", "-C", "0", "-i", input, a, b), qt.Equals, `runs diverge at step 6: ptr differs
+  a: step 6 at 1:6: < ptr=0 cell=1
+  b: step 6 at 1:6: + ptr=1 cell=2
+`)
+		a = write("fail.bf", "+[<]")
+		b = write("clear.bf", "+[-]")
+		c.Assert(execute("--run", "-C", "0", a, b), qt.Equals, `runs diverge at step 3: run a failed: array index can't be less than zero at 1:3 (line:column)
+  a: step 3 at 1:3: < ptr=0 cell=1, failed
+  b: step 3 at 1:3: - ptr=0 cell=0
+`)
+	})
+}
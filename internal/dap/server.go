@@ -0,0 +1,579 @@
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/internal/wire"
+)
+
+const (
+	threadID = 1
+
+	tapeRef    = 1 // variables reference of the tape scope
+	machineRef = 2 // variables reference of the machine scope
+
+	// tapeMargin is the number of cells shown after the last interesting one
+	tapeMargin = 4
+)
+
+// Serve runs a debug session, reading the requests from r and writing the responses
+// and the events to w. It returns when the client disconnects or r is closed.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &session{
+		r:      bufio.NewReader(r),
+		w:      w,
+		lines1: true,
+		cols1:  true,
+	}
+
+	defer s.wg.Wait()
+	for {
+		var req request
+		err := wire.Read(s.r, &req)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		if req.Command == "disconnect" || req.Command == "terminate" {
+			s.interrupt()
+			s.respond(req, nil)
+			return nil
+		}
+		s.handle(req)
+	}
+}
+
+// session is the state of a debug session
+type session struct {
+	r *bufio.Reader
+
+	wmu sync.Mutex // guards w and seq
+	w   io.Writer
+	seq int
+
+	lines1, cols1 bool // whether lines and columns start at 1 for the client
+
+	// imu guards bfi for interrupt, which can not wait for mu while the program runs.
+	// bfi is only changed with both mu and imu held.
+	imu sync.Mutex
+
+	// mu guards the fields below, and is held while the program runs
+	mu          sync.Mutex
+	bfi         *bf.BF
+	src         []byte
+	path        string
+	bps         map[string][]breakpointRequest // by source path
+	watches     []string
+	stopOnEntry bool
+	launched    bool
+	configured  bool
+
+	running int32 // accessed atomically
+	wg      sync.WaitGroup
+}
+
+// breakpointRequest is a breakpoint requested by the client, in 1-based lines and columns
+type breakpointRequest struct {
+	line, column int
+}
+
+func (s *session) send(v interface{}) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.seq++
+	switch m := v.(type) {
+	case *response:
+		m.Seq = s.seq
+	case *event:
+		m.Seq = s.seq
+	}
+	_ = wire.Write(s.w, v)
+}
+
+func (s *session) respond(req request, body interface{}) {
+	s.send(&response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: true, Body: body})
+}
+
+func (s *session) fail(req request, err error) {
+	s.send(&response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Message: err.Error()})
+}
+
+func (s *session) event(name string, body interface{}) {
+	s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func (s *session) isRunning() bool {
+	return atomic.LoadInt32(&s.running) == 1
+}
+
+// interrupt stops the program if it runs
+func (s *session) interrupt() {
+	s.imu.Lock()
+	defer s.imu.Unlock()
+
+	if s.bfi != nil && s.isRunning() {
+		s.bfi.Interrupt()
+	}
+}
+
+// handle answers a request, other than disconnect
+func (s *session) handle(req request) {
+	switch req.Command {
+	case "initialize":
+		var args initializeArguments
+		_ = json.Unmarshal(req.Arguments, &args)
+		if args.LinesStartAt1 != nil {
+			s.lines1 = *args.LinesStartAt1
+		}
+		if args.ColumnsStartAt1 != nil {
+			s.cols1 = *args.ColumnsStartAt1
+		}
+
+		s.respond(req, map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsStepBack":                 true,
+			"supportsDataBreakpoints":          true,
+		})
+		s.event("initialized", nil)
+	case "threads":
+		s.respond(req, map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "main"}},
+		})
+	case "pause":
+		s.interrupt()
+		s.respond(req, nil)
+	default:
+		if s.isRunning() {
+			s.fail(req, errors.New("the program is running"))
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		err := s.handleStopped(req)
+		if err != nil {
+			s.fail(req, err)
+		}
+	}
+}
+
+// handleStopped answers the requests that need the program to be stopped
+func (s *session) handleStopped(req request) error {
+	switch req.Command {
+	case "launch":
+		return s.launch(req)
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		err := json.Unmarshal(req.Arguments, &args)
+		if err != nil {
+			return err
+		}
+
+		bps := make([]breakpointRequest, len(args.Breakpoints))
+		for i, b := range args.Breakpoints {
+			bps[i] = breakpointRequest{line: s.fromClientLine(b.Line), column: s.fromClientColumn(b.Column)}
+		}
+		if s.bps == nil {
+			s.bps = make(map[string][]breakpointRequest)
+		}
+		path := cleanPath(args.Source.Path)
+		s.bps[path] = bps
+		s.respond(req, map[string]interface{}{"breakpoints": s.setBreakpoints(path)})
+	case "dataBreakpointInfo":
+		var args dataBreakpointInfoArguments
+		err := json.Unmarshal(req.Arguments, &args)
+		if err != nil {
+			return err
+		}
+
+		body := map[string]interface{}{"dataId": nil, "description": "only cells can be watched"}
+		if args.VariablesReference == tapeRef {
+			body = map[string]interface{}{
+				"dataId":      args.Name,
+				"description": args.Name,
+				"accessTypes": []string{"write"},
+			}
+		}
+		s.respond(req, body)
+	case "setDataBreakpoints":
+		var args setDataBreakpointsArguments
+		err := json.Unmarshal(req.Arguments, &args)
+		if err != nil {
+			return err
+		}
+
+		s.watches = s.watches[:0]
+		for _, b := range args.Breakpoints {
+			s.watches = append(s.watches, b.DataID+b.Condition)
+		}
+		s.respond(req, map[string]interface{}{"breakpoints": s.setWatchpoints()})
+	case "configurationDone":
+		s.configured = true
+		s.respond(req, nil)
+		s.start()
+	case "stackTrace":
+		if s.bfi == nil {
+			return errors.New("no program is launched")
+		}
+		frames := s.stackTrace()
+		s.respond(req, map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)})
+	case "scopes":
+		s.respond(req, map[string]interface{}{"scopes": []scope{
+			{Name: "Tape", VariablesReference: tapeRef},
+			{Name: "Machine", VariablesReference: machineRef},
+		}})
+	case "variables":
+		if s.bfi == nil {
+			return errors.New("no program is launched")
+		}
+		var args variablesArguments
+		err := json.Unmarshal(req.Arguments, &args)
+		if err != nil {
+			return err
+		}
+		s.respond(req, map[string]interface{}{"variables": s.variables(args.VariablesReference)})
+	case "continue":
+		s.respond(req, map[string]bool{"allThreadsContinued": true})
+		s.resume(s.bfi.Continue, "breakpoint")
+	case "next":
+		s.respond(req, nil)
+		s.resume(s.bfi.StepOver, "step")
+	case "stepIn":
+		s.respond(req, nil)
+		s.resume(s.bfi.Step, "step")
+	case "stepOut":
+		s.respond(req, nil)
+		s.resume(s.bfi.StepOut, "step")
+	case "stepBack":
+		s.respond(req, nil)
+		s.resume(s.bfi.StepBack, "step")
+	case "reverseContinue":
+		s.respond(req, nil)
+		s.resume(s.bfi.ReverseContinue, "breakpoint")
+	default:
+		return fmt.Errorf("unsupported command %q", req.Command)
+	}
+
+	return nil
+}
+
+// launch loads the program, and starts it once the configuration is done
+func (s *session) launch(req request) error {
+	var args launchArguments
+	err := json.Unmarshal(req.Arguments, &args)
+	if err != nil {
+		return err
+	}
+
+	s.src, err = os.ReadFile(args.Program)
+	if err != nil {
+		return fmt.Errorf("failed to read program: %w", err)
+	}
+	s.path = cleanPath(args.Program)
+
+	inp := []byte(args.Input)
+	if args.InputFile != "" {
+		inp, err = os.ReadFile(args.InputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read input file: %w", err)
+		}
+	}
+
+	bfi, err := bf.New(bytes.NewReader(s.src), outputWriter{s}, bytes.NewReader(inp), bf.WithHistory(0))
+	if err != nil {
+		return err
+	}
+	s.imu.Lock()
+	s.bfi = bfi
+	s.imu.Unlock()
+	s.stopOnEntry = args.StopOnEntry
+	s.launched = true
+
+	s.setBreakpoints(s.path)
+	s.setWatchpoints()
+	s.respond(req, nil)
+	s.start()
+
+	return nil
+}
+
+// start runs the program once it is both launched and configured
+func (s *session) start() {
+	if !s.launched || !s.configured {
+		return
+	}
+
+	if s.stopOnEntry {
+		s.event("stopped", map[string]interface{}{"reason": "entry", "threadId": threadID, "allThreadsStopped": true})
+		return
+	}
+	s.resume(s.bfi.Continue, "breakpoint")
+}
+
+// resume runs the program with run in the background, and reports why it stopped.
+// It is called with mu held, which the background run takes once the request is done.
+func (s *session) resume(run func() (bf.Position, error), reason string) {
+	atomic.StoreInt32(&s.running, 1)
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		s.mu.Lock()
+
+		_, err := run()
+		if err == nil && s.bfi.Done() {
+			err = io.EOF
+		}
+		hits := s.bfi.WatchHits()
+		pos := s.bfi.Pos()
+		for _, b := range s.bfi.Breakpoints() {
+			if b.Offset == pos.Offset {
+				reason = "breakpoint"
+			}
+		}
+
+		atomic.StoreInt32(&s.running, 0)
+		s.mu.Unlock()
+
+		body := map[string]interface{}{"reason": reason, "threadId": threadID, "allThreadsStopped": true}
+		switch {
+		case errors.Is(err, io.EOF):
+			s.event("exited", map[string]int{"exitCode": 0})
+			s.event("terminated", nil)
+			return
+		case err != nil && !errors.Is(err, bf.ErrInterrupted) && !errors.Is(err, bf.ErrNoHistory):
+			s.event("output", map[string]string{"category": "stderr", "output": fmt.Sprintf("error: %v\n", err)})
+			s.event("exited", map[string]int{"exitCode": 1})
+			s.event("terminated", nil)
+			return
+		case errors.Is(err, bf.ErrInterrupted):
+			body["reason"] = "pause"
+		case errors.Is(err, bf.ErrNoHistory):
+			body["reason"] = "step"
+			body["description"] = "at the start of the recorded history"
+		case len(hits) > 0:
+			descs := make([]string, len(hits))
+			for i, h := range hits {
+				descs[i] = h.String()
+			}
+			body["reason"] = "data breakpoint"
+			body["description"] = strings.Join(descs, ", ")
+		}
+		s.event("stopped", body)
+	}()
+}
+
+// setBreakpoints replaces the breakpoints of the program with the ones requested for
+// the source path, if it is the program. The breakpoints of other sources are not
+// verified.
+func (s *session) setBreakpoints(path string) []breakpoint {
+	bps := s.bps[path]
+	res := make([]breakpoint, len(bps))
+	if s.bfi == nil || path != s.path {
+		// verified once the program is launched
+		for i, b := range bps {
+			res[i] = breakpoint{ID: i + 1, Line: s.toClientLine(b.line)}
+			if s.bfi != nil {
+				res[i].Message = "not in the launched program"
+			}
+		}
+		return res
+	}
+
+	for _, p := range s.bfi.Breakpoints() {
+		s.bfi.ClearBreakpoint(p.Offset)
+	}
+	for i, b := range bps {
+		res[i] = breakpoint{ID: i + 1, Line: s.toClientLine(b.line)}
+
+		col := b.column
+		if col < 1 {
+			col = 1
+		}
+		p, err := s.bfi.SetBreakpointAt(b.line, col)
+		if err != nil {
+			res[i].Message = err.Error()
+			continue
+		}
+
+		res[i].Verified = true
+		res[i].Line = s.toClientLine(p.Line)
+		res[i].Column = s.toClientColumn(p.Column)
+	}
+
+	return res
+}
+
+// setWatchpoints replaces the watchpoints of the program with the requested ones
+func (s *session) setWatchpoints() []breakpoint {
+	res := make([]breakpoint, len(s.watches))
+	if s.bfi == nil {
+		return res
+	}
+
+	for _, w := range s.bfi.Watchpoints() {
+		s.bfi.RemoveWatchpoint(w.ID)
+	}
+	for i, expr := range s.watches {
+		w, err := bf.ParseWatchpoint(expr)
+		if err != nil {
+			res[i].Message = err.Error()
+			continue
+		}
+
+		res[i].ID = s.bfi.AddWatchpoint(w)
+		res[i].Verified = true
+	}
+
+	return res
+}
+
+// cleanPath returns the absolute form of path, to compare the paths of the sources
+func cleanPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// stackTrace reports the loop stack as a call stack: the top frame is the next
+// command, and each loop is a frame located at its opening bracket
+func (s *session) stackTrace() []stackFrame {
+	src := source{Name: filepath.Base(s.path), Path: s.path}
+	loops := s.bfi.LoopStack()
+
+	name := func(depth int) string {
+		if depth == 0 {
+			return "main"
+		}
+		return "loop " + loops[depth-1].String()
+	}
+
+	p := s.bfi.Pos()
+	if s.bfi.Done() && len(s.src) > 0 {
+		p = s.bfi.Position(len(s.src) - 1)
+	}
+	frames := []stackFrame{{
+		ID:     0,
+		Name:   name(len(loops)),
+		Source: src,
+		Line:   s.toClientLine(p.Line),
+		Column: s.toClientColumn(p.Column),
+	}}
+
+	for i := len(loops) - 1; i >= 0; i-- {
+		frames = append(frames, stackFrame{
+			ID:     len(frames),
+			Name:   name(i),
+			Source: src,
+			Line:   s.toClientLine(loops[i].Line),
+			Column: s.toClientColumn(loops[i].Column),
+		})
+	}
+
+	return frames
+}
+
+// variables lists the cells of the tape, or the state of the machine
+func (s *session) variables(ref int) []variable {
+	ptr := s.bfi.Pointer()
+
+	if ref == machineRef {
+		vars := []variable{
+			{Name: "pointer", Value: strconv.Itoa(ptr), Type: "int"},
+			{Name: "cell", Value: strconv.Itoa(int(s.bfi.Cell(ptr))), Type: "int32"},
+			{Name: "loop depth", Value: strconv.Itoa(len(s.bfi.LoopStack())), Type: "int"},
+		}
+		if !s.bfi.Done() {
+			vars = append(vars, variable{Name: "next command", Value: strconv.QuoteRune(rune(s.src[s.bfi.Pos().Offset]))})
+		}
+		return vars
+	}
+	if ref != tapeRef {
+		return nil
+	}
+
+	// show the tape up to the data pointer or the last non-zero cell, with a margin
+	tape := s.bfi.Tape()
+	last := ptr
+	for i := len(tape) - 1; i > last; i-- {
+		if tape[i] != 0 {
+			last = i
+			break
+		}
+	}
+	last += tapeMargin
+
+	vars := make([]variable, 0, last+1)
+	for i := 0; i <= last; i++ {
+		v := variable{Name: fmt.Sprintf("cell[%d]", i), Value: strconv.Itoa(int(s.bfi.Cell(i))), Type: "int32"}
+		if i == ptr {
+			v.Value += " <- ptr"
+		}
+		vars = append(vars, v)
+	}
+
+	return vars
+}
+
+func (s *session) toClientLine(l int) int {
+	if s.lines1 {
+		return l
+	}
+	return l - 1
+}
+
+func (s *session) toClientColumn(c int) int {
+	if s.cols1 {
+		return c
+	}
+	return c - 1
+}
+
+func (s *session) fromClientLine(l int) int {
+	if s.lines1 {
+		return l
+	}
+	return l + 1
+}
+
+func (s *session) fromClientColumn(c int) int {
+	if s.cols1 || c == 0 {
+		return c
+	}
+	return c + 1
+}
+
+// outputWriter sends the program output as output events
+type outputWriter struct {
+	s *session
+}
+
+func (o outputWriter) Write(p []byte) (int, error) {
+	o.s.event("output", map[string]string{"category": "stdout", "output": string(p)})
+	return len(p), nil
+}
@@ -0,0 +1,287 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/thesoulless/bf/internal/wire"
+)
+
+// client is a scripted DAP client talking to an in-process server
+type client struct {
+	c    *qt.C
+	w    io.WriteCloser
+	msgs chan message // read in the background, so the server never blocks on writing
+	seq  int
+	done chan error
+}
+
+// message is any message sent by the server
+type message struct {
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+func newClient(c *qt.C) *client {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	cl := &client{c: c, w: cw, msgs: make(chan message, 1024), done: make(chan error, 1)}
+	go func() {
+		err := Serve(sr, sw)
+		sw.Close()
+		cl.done <- err
+	}()
+	go func() {
+		r := bufio.NewReader(cr)
+		for {
+			var m message
+			err := wire.Read(r, &m)
+			if err != nil {
+				close(cl.msgs)
+				return
+			}
+			cl.msgs <- m
+		}
+	}()
+
+	return cl
+}
+
+func (cl *client) send(command string, args interface{}) int {
+	cl.seq++
+	req := map[string]interface{}{"seq": cl.seq, "type": "request", "command": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	err := wire.Write(cl.w, req)
+	cl.c.Assert(err, qt.IsNil)
+	return cl.seq
+}
+
+// read returns the next message of the server, failing after a timeout
+func (cl *client) read() message {
+	select {
+	case m, ok := <-cl.msgs:
+		if !ok {
+			cl.c.Fatal("the server closed the connection")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		cl.c.Fatal("timed out waiting for the server")
+		return message{}
+	}
+}
+
+// request sends a request and returns its response, collecting the events sent before it
+func (cl *client) request(command string, args interface{}) (message, []message) {
+	seq := cl.send(command, args)
+
+	var events []message
+	for {
+		m := cl.read()
+		if m.Type == "response" && m.RequestSeq == seq {
+			return m, events
+		}
+		events = append(events, m)
+	}
+}
+
+// waitEvent reads messages until the named event, and returns it
+func (cl *client) waitEvent(name string) message {
+	for {
+		m := cl.read()
+		if m.Type == "event" && m.Event == name {
+			return m
+		}
+	}
+}
+
+func decode(c *qt.C, m message, v interface{}) {
+	err := json.Unmarshal(m.Body, v)
+	c.Assert(err, qt.IsNil)
+}
+
+func TestServe(t *testing.T) {
+	c := qt.New(t)
+
+	program, err := filepath.Abs("../../testdata/test.bf")
+	c.Assert(err, qt.IsNil)
+
+	t.Run("session", func(t *testing.T) {
+		cl := newClient(c)
+
+		res, _ := cl.request("initialize", map[string]interface{}{"adapterID": "bf"})
+		c.Assert(res.Success, qt.IsTrue)
+		var caps map[string]bool
+		decode(c, res, &caps)
+		c.Assert(caps["supportsStepBack"], qt.IsTrue)
+		cl.waitEvent("initialized")
+
+		res, _ = cl.request("launch", map[string]interface{}{"program": program, "stopOnEntry": true})
+		c.Assert(res.Success, qt.IsTrue, qt.Commentf(res.Message))
+
+		res, _ = cl.request("setBreakpoints", map[string]interface{}{
+			"source":      map[string]string{"path": program},
+			"breakpoints": []map[string]int{{"line": 2}, {"line": 9}},
+		})
+		var bps struct {
+			Breakpoints []breakpoint `json:"breakpoints"`
+		}
+		decode(c, res, &bps)
+		c.Assert(bps.Breakpoints, qt.DeepEquals, []breakpoint{
+			{ID: 1, Verified: true, Line: 2, Column: 1},
+			{ID: 2, Line: 9, Message: "position does not point to a command"},
+		})
+
+		cl.request("configurationDone", nil)
+		m := cl.waitEvent("stopped")
+		c.Assert(string(m.Body), qt.Contains, `"reason":"entry"`)
+
+		cl.request("continue", map[string]int{"threadId": threadID})
+		m = cl.waitEvent("stopped")
+		c.Assert(string(m.Body), qt.Contains, `"reason":"breakpoint"`)
+
+		res, _ = cl.request("stackTrace", map[string]int{"threadId": threadID})
+		var st struct {
+			StackFrames []stackFrame `json:"stackFrames"`
+		}
+		decode(c, res, &st)
+		c.Assert(st.StackFrames, qt.HasLen, 1)
+		c.Assert(st.StackFrames[0].Name, qt.Equals, "main")
+		c.Assert(st.StackFrames[0].Line, qt.Equals, 2)
+
+		res, _ = cl.request("variables", map[string]int{"variablesReference": tapeRef})
+		var vars struct {
+			Variables []variable `json:"variables"`
+		}
+		decode(c, res, &vars)
+		c.Assert(vars.Variables[2], qt.DeepEquals, variable{Name: "cell[2]", Value: "72", Type: "int32"})
+
+		res, _ = cl.request("variables", map[string]int{"variablesReference": machineRef})
+		decode(c, res, &vars)
+		c.Assert(vars.Variables[0], qt.DeepEquals, variable{Name: "pointer", Value: "0", Type: "int"})
+
+		// step back into the loop before the breakpoint
+		cl.request("stepBack", map[string]int{"threadId": threadID})
+		cl.waitEvent("stopped")
+		res, _ = cl.request("stackTrace", map[string]int{"threadId": threadID})
+		decode(c, res, &st)
+		c.Assert(st.StackFrames, qt.HasLen, 2)
+		c.Assert(st.StackFrames[0].Name, qt.Equals, "loop 1:9")
+		c.Assert(st.StackFrames[1].Line, qt.Equals, 1)
+		c.Assert(st.StackFrames[1].Column, qt.Equals, 9)
+
+		res, _ = cl.request("setBreakpoints", map[string]interface{}{
+			"source":      map[string]string{"path": program},
+			"breakpoints": []map[string]int{},
+		})
+		c.Assert(res.Success, qt.IsTrue)
+
+		res, _ = cl.request("dataBreakpointInfo", map[string]interface{}{"variablesReference": tapeRef, "name": "cell[3]"})
+		var info struct {
+			DataID string `json:"dataId"`
+		}
+		decode(c, res, &info)
+		c.Assert(info.DataID, qt.Equals, "cell[3]")
+		cl.request("setDataBreakpoints", map[string]interface{}{
+			"breakpoints": []map[string]string{{"dataId": info.DataID, "condition": "==111"}},
+		})
+
+		cl.request("continue", map[string]int{"threadId": threadID})
+		m = cl.waitEvent("stopped")
+		c.Assert(string(m.Body), qt.Contains, `"reason":"data breakpoint"`)
+		c.Assert(string(m.Body), qt.Contains, `cell[3]==111`)
+
+		cl.request("setDataBreakpoints", map[string]interface{}{"breakpoints": []interface{}{}})
+		cl.send("continue", map[string]int{"threadId": threadID})
+
+		var out string
+		for {
+			m := cl.read()
+			if m.Event == "terminated" {
+				break
+			}
+			if m.Event == "output" {
+				var o struct {
+					Output string `json:"output"`
+				}
+				decode(c, m, &o)
+				out += o.Output
+			}
+		}
+		c.Assert(out, qt.Equals, "o World!\n")
+
+		cl.request("disconnect", nil)
+		c.Assert(<-cl.done, qt.IsNil)
+	})
+
+	t.Run("breakpoints of other sources", func(t *testing.T) {
+		cl := newClient(c)
+		cl.request("initialize", nil)
+		cl.request("launch", map[string]interface{}{"program": program})
+
+		res, _ := cl.request("setBreakpoints", map[string]interface{}{
+			"source":      map[string]string{"path": filepath.Join(filepath.Dir(program), "other.bf")},
+			"breakpoints": []map[string]int{{"line": 2}},
+		})
+		var bps struct {
+			Breakpoints []breakpoint `json:"breakpoints"`
+		}
+		decode(c, res, &bps)
+		c.Assert(bps.Breakpoints, qt.DeepEquals, []breakpoint{
+			{ID: 1, Line: 2, Message: "not in the launched program"},
+		})
+
+		// the program runs to the end
+		cl.request("configurationDone", nil)
+		for {
+			m := cl.read()
+			c.Assert(m.Event, qt.Not(qt.Equals), "stopped")
+			if m.Event == "terminated" {
+				break
+			}
+		}
+
+		cl.request("disconnect", nil)
+		c.Assert(<-cl.done, qt.IsNil)
+	})
+
+	t.Run("pause", func(t *testing.T) {
+		dir := t.TempDir()
+		loop := filepath.Join(dir, "loop.bf")
+		err := os.WriteFile(loop, []byte("+[]"), 0o600)
+		c.Assert(err, qt.IsNil)
+
+		cl := newClient(c)
+		cl.request("initialize", nil)
+		cl.request("launch", map[string]interface{}{"program": loop})
+		cl.request("configurationDone", nil)
+
+		res, _ := cl.request("stackTrace", map[string]int{"threadId": threadID})
+		c.Assert(res.Success, qt.IsFalse)
+		c.Assert(res.Message, qt.Equals, "the program is running")
+
+		cl.request("pause", map[string]int{"threadId": threadID})
+		m := cl.waitEvent("stopped")
+		c.Assert(string(m.Body), qt.Contains, `"reason":"pause"`)
+
+		res, _ = cl.request("stackTrace", map[string]int{"threadId": threadID})
+		c.Assert(res.Success, qt.IsTrue)
+
+		cl.request("disconnect", nil)
+		c.Assert(<-cl.done, qt.IsNil)
+	})
+}
@@ -0,0 +1,471 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/thesoulless/bf/ast"
+	"github.com/thesoulless/bf/format"
+	"github.com/thesoulless/bf/internal/syntax"
+	"github.com/thesoulless/bf/internal/wire"
+)
+
+// semantic token types, in the order of the legend
+const (
+	tokenOperator = iota // > < + -
+	tokenKeyword         // [ ]
+	tokenFunction        // . ,
+	tokenComment
+)
+
+var tokenTypes = []string{"operator", "keyword", "function", "comment"}
+
+var errExitBeforeShutdown = errors.New("exit notification received before shutdown")
+
+// descriptions of the commands, shown on hover
+var descriptions = map[byte]string{
+	'>': "moves the data pointer to the right",
+	'<': "moves the data pointer to the left",
+	'+': "increments the current cell",
+	'-': "decrements the current cell",
+	'.': "writes the current cell to the output",
+	',': "reads a character of the input into the current cell",
+	'[': "starts a loop, skipped when the current cell is zero",
+	']': "jumps back to the start of the loop unless the current cell is zero",
+}
+
+// Serve runs a language server, reading the messages of the client from r and writing
+// the responses and the notifications to w. It returns when the client sends the exit
+// notification or r is closed.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{w: w, docs: make(map[string]*document)}
+	br := bufio.NewReader(r)
+
+	for {
+		var msg incoming
+		err := wire.Read(br, &msg)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errExitBeforeShutdown
+			}
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+// server is the state of a language server
+type server struct {
+	w        io.Writer
+	docs     map[string]*document // open documents, keyed by URI
+	shutdown bool
+}
+
+// document is an open document
+type document struct {
+	text string
+	file *syntax.File
+	errs syntax.ErrorList
+}
+
+func newDocument(text string) *document {
+	d := &document{text: text}
+
+	var err error
+	d.file, err = syntax.Parse([]byte(text))
+	if err != nil && !errors.As(err, &d.errs) {
+		d.errs = syntax.ErrorList{{Err: err}}
+	}
+
+	return d
+}
+
+func (s *server) respond(msg incoming, result interface{}) {
+	_ = wire.Write(s.w, &response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *server) fail(msg incoming, code int, err error) {
+	_ = wire.Write(s.w, &errorResponse{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Error:   responseError{Code: code, Message: err.Error()},
+	})
+}
+
+func (s *server) notify(method string, params interface{}) {
+	_ = wire.Write(s.w, &notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *server) handle(msg incoming) {
+	isRequest := len(msg.ID) > 0
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch msg.Method {
+	case "initialize":
+		result = capabilities()
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			s.open(p.TextDocument.URI, p.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var p didChangeParams
+		// the document is synced in full, so the last change is the whole text
+		if err = json.Unmarshal(msg.Params, &p); err == nil && len(p.ContentChanges) > 0 {
+			s.open(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			delete(s.docs, p.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+				URI:         p.TextDocument.URI,
+				Diagnostics: []diagnostic{},
+			})
+		}
+	case "textDocument/hover":
+		result, err = s.withPosition(msg, (*document).hover)
+	case "textDocument/definition":
+		result, err = s.withPosition(msg, func(d *document, uri string, offset int) interface{} {
+			return d.definition(uri, offset)
+		})
+	case "textDocument/formatting":
+		var p formattingParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			var d *document
+			if d, err = s.document(p.TextDocument.URI); err == nil {
+				result = d.format(p.Options.InsertSpaces, p.Options.TabSize)
+			}
+		}
+	case "textDocument/foldingRange":
+		var p documentParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			var d *document
+			if d, err = s.document(p.TextDocument.URI); err == nil {
+				result = d.foldingRanges()
+			}
+		}
+	case "textDocument/semanticTokens/full":
+		var p documentParams
+		if err = json.Unmarshal(msg.Params, &p); err == nil {
+			var d *document
+			if d, err = s.document(p.TextDocument.URI); err == nil {
+				result = semanticTokens{Data: d.semanticTokens()}
+			}
+		}
+	default:
+		if isRequest {
+			s.fail(msg, codeMethodNotFound, fmt.Errorf("method %q is not supported", msg.Method))
+		}
+		return
+	}
+
+	if !isRequest {
+		return
+	}
+	if err != nil {
+		s.fail(msg, codeInvalidParams, err)
+		return
+	}
+	s.respond(msg, result)
+}
+
+func capabilities() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":           1, // full
+			"hoverProvider":              true,
+			"definitionProvider":         true,
+			"documentFormattingProvider": true,
+			"foldingRangeProvider":       true,
+			"semanticTokensProvider": map[string]interface{}{
+				"legend": map[string]interface{}{
+					"tokenTypes":     tokenTypes,
+					"tokenModifiers": []string{},
+				},
+				"full": true,
+			},
+		},
+		"serverInfo": map[string]string{"name": "bf"},
+	}
+}
+
+func (s *server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, fmt.Errorf("document %q is not open", uri)
+	}
+	return d, nil
+}
+
+// withPosition decodes the position parameters of msg, and calls fn with the document
+// and the offset they point to
+func (s *server) withPosition(msg incoming, fn func(d *document, uri string, offset int) interface{}) (interface{}, error) {
+	var p textDocumentPositionParams
+	err := json.Unmarshal(msg.Params, &p)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := d.offset(p.Position)
+	if offset >= len(d.text) {
+		return nil, nil
+	}
+	return fn(d, p.TextDocument.URI, offset), nil
+}
+
+// open stores the text of a document and publishes its diagnostics
+func (s *server) open(uri, text string) {
+	d := newDocument(text)
+	s.docs[uri] = d
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: d.diagnostics()})
+}
+
+func (d *document) diagnostics() []diagnostic {
+	diags := []diagnostic{}
+	for _, e := range d.errs {
+		msg := e.Err.Error()
+		if errors.Is(e.Err, syntax.ErrLoopDoesNotMatch) {
+			msg = fmt.Sprintf("unmatched %c", d.text[int(e.Pos)])
+		}
+		diags = append(diags, diagnostic{
+			Range:    d.rangeOf(int(e.Pos), int(e.Pos)+1),
+			Severity: 1, // error
+			Source:   "bf",
+			Message:  msg,
+		})
+	}
+
+	return diags
+}
+
+// loopAt returns the loop with a bracket at offset, and its nesting depth
+func (d *document) loopAt(offset int) (*syntax.Loop, int) {
+	loops := d.file.Enclosing(offset)
+	if len(loops) == 0 {
+		return nil, 0
+	}
+	l := loops[len(loops)-1]
+	if l.Open != offset && l.Close != offset {
+		return nil, 0
+	}
+	return l, len(loops) - 1
+}
+
+func (d *document) hover(_ string, offset int) interface{} {
+	ch := d.text[offset]
+	if !ast.IsCommand(ch) {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**`%c`** %s", ch, descriptions[ch])
+
+	loops := d.file.Enclosing(offset)
+	if l, depth := d.loopAt(offset); l != nil {
+		fmt.Fprintf(&b, "\n\nloop %s to %s, nesting depth %d", d.lineCol(l.Open), d.lineCol(l.Close), depth)
+		if n, ok := l.Net(); ok {
+			fmt.Fprintf(&b, "\n\npointer movement per iteration: %+d", n)
+		} else {
+			b.WriteString("\n\npointer movement per iteration: unknown, an inner loop moves the pointer")
+		}
+	} else if (ch == '[' || ch == ']') && !d.matched(offset) {
+		b.WriteString("\n\nthe bracket is unmatched")
+	} else {
+		fmt.Fprintf(&b, "\n\nnesting depth %d", len(loops))
+	}
+
+	return hover{
+		Contents: markupContent{Kind: "markdown", Value: b.String()},
+		Range:    d.rangeOf(offset, offset+1),
+	}
+}
+
+// matched reports whether the bracket at offset is not reported as unmatched
+func (d *document) matched(offset int) bool {
+	for _, e := range d.errs {
+		if int(e.Pos) == offset {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *document) definition(uri string, offset int) interface{} {
+	l, _ := d.loopAt(offset)
+	if l == nil {
+		return nil
+	}
+
+	other := l.Open
+	if offset == l.Open {
+		other = l.Close
+	}
+	return location{URI: uri, Range: d.rangeOf(other, other+1)}
+}
+
+// format formats the document like bf fmt, indenting loops with tabs, or tabSize spaces.
+// A document with errors is left as it is.
+func (d *document) format(spaces bool, tabSize int) []textEdit {
+	c := format.Config{Indent: "\t"}
+	if spaces {
+		if tabSize <= 0 {
+			tabSize = 4
+		}
+		c.Indent = strings.Repeat(" ", tabSize)
+	}
+
+	src, err := format.Source([]byte(d.text), c)
+	if err != nil || string(src) == d.text {
+		return []textEdit{}
+	}
+	return []textEdit{{Range: d.rangeOf(0, len(d.text)), NewText: string(src)}}
+}
+
+// foldingRanges returns a range for each loop spanning more than two lines, keeping
+// the line of the closing bracket visible
+func (d *document) foldingRanges() []foldingRange {
+	ranges := []foldingRange{}
+	d.file.Inspect(func(n syntax.Node, _ []*syntax.Loop) bool {
+		l, ok := n.(*syntax.Loop)
+		if !ok {
+			return true
+		}
+
+		start, end := d.pos(l.Open).Line, d.pos(l.Close).Line-1
+		if end > start {
+			ranges = append(ranges, foldingRange{StartLine: start, EndLine: end, Kind: "region"})
+		}
+		return true
+	})
+
+	return ranges
+}
+
+// semanticTokens returns the encoded tokens of the document: runs of commands of the
+// same kind, and the words of the comments
+func (d *document) semanticTokens() []int {
+	var data []int
+	prevLine, prevChar := 0, 0
+
+	for i, start := range d.file.Lines {
+		end := len(d.text)
+		if i+1 < len(d.file.Lines) {
+			end = d.file.Lines[i+1] - 1
+		}
+
+		char := 0 // UTF-16 offset in the line
+		for off := start; off < end; {
+			typ := tokenType(d.text[off])
+			run := off
+			for run < end && tokenType(d.text[run]) == typ {
+				_, size := utf8.DecodeRuneInString(d.text[run:])
+				run += size
+			}
+			n := utf16Len(d.text[off:run])
+
+			if typ >= 0 {
+				if i != prevLine {
+					prevChar = 0
+				}
+				data = append(data, i-prevLine, char-prevChar, n, typ, 0)
+				prevLine, prevChar = i, char
+			}
+			char += n
+			off = run
+		}
+	}
+
+	if data == nil {
+		data = []int{}
+	}
+	return data
+}
+
+// tokenType returns the semantic token type of ch, or -1 for whitespace
+func tokenType(ch byte) int {
+	switch ch {
+	case '>', '<', '+', '-':
+		return tokenOperator
+	case '[', ']':
+		return tokenKeyword
+	case '.', ',':
+		return tokenFunction
+	case ' ', '\t', '\r', '\n':
+		return -1
+	}
+	return tokenComment
+}
+
+func (d *document) pos(offset int) position {
+	line := sort.SearchInts(d.file.Lines, offset+1) - 1
+	return position{Line: line, Character: utf16Len(d.text[d.file.Lines[line]:offset])}
+}
+
+// lineCol returns offset as "line:column", both starting at 1, as the interpreter reports it
+func (d *document) lineCol(offset int) string {
+	line, col := d.file.Position(offset)
+	return fmt.Sprintf("%d:%d", line, col)
+}
+
+func (d *document) rangeOf(start, end int) rangeLSP {
+	return rangeLSP{Start: d.pos(start), End: d.pos(end)}
+}
+
+// offset returns the offset of p, clamped to the end of its line
+func (d *document) offset(p position) int {
+	if p.Line < 0 {
+		return 0
+	}
+	if p.Line >= len(d.file.Lines) {
+		return len(d.text)
+	}
+
+	off := d.file.Lines[p.Line]
+	for char := 0; char < p.Character && off < len(d.text) && d.text[off] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[off:])
+		char += runeLen(r)
+		off += size
+	}
+
+	return off
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeLen(r)
+	}
+	return n
+}
+
+// runeLen returns the number of UTF-16 code units of r
+func runeLen(r rune) int {
+	if utf16.IsSurrogate(r) || r < 0x10000 {
+		return 1 // invalid runes are replaced by U+FFFD
+	}
+	return 2
+}
@@ -0,0 +1,213 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/thesoulless/bf/format"
+	"github.com/thesoulless/bf/internal/wire"
+)
+
+const uri = "file:///tmp/test.bf"
+
+// client is a scripted LSP client talking to an in-process server
+type client struct {
+	c    *qt.C
+	w    io.WriteCloser
+	msgs chan message // read in the background, so the server never blocks on writing
+	id   int
+	done chan error
+}
+
+// message is any message sent by the server
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+func newClient(c *qt.C) *client {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	cl := &client{c: c, w: cw, msgs: make(chan message, 1024), done: make(chan error, 1)}
+	go func() {
+		err := Serve(sr, sw)
+		sw.Close()
+		cl.done <- err
+	}()
+	go func() {
+		r := bufio.NewReader(cr)
+		for {
+			var m message
+			err := wire.Read(r, &m)
+			if err != nil {
+				close(cl.msgs)
+				return
+			}
+			cl.msgs <- m
+		}
+	}()
+
+	return cl
+}
+
+func (cl *client) notify(method string, params interface{}) {
+	err := wire.Write(cl.w, map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+	cl.c.Assert(err, qt.IsNil)
+}
+
+// read returns the next message of the server, failing after a timeout
+func (cl *client) read() message {
+	select {
+	case m, ok := <-cl.msgs:
+		if !ok {
+			cl.c.Fatal("the server closed the connection")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		cl.c.Fatal("timed out waiting for the server")
+		return message{}
+	}
+}
+
+// request sends a request and returns its response, skipping the notifications sent before it
+func (cl *client) request(method string, params interface{}) message {
+	cl.id++
+	err := wire.Write(cl.w, map[string]interface{}{"jsonrpc": "2.0", "id": cl.id, "method": method, "params": params})
+	cl.c.Assert(err, qt.IsNil)
+
+	for {
+		m := cl.read()
+		if m.ID != nil && *m.ID == cl.id {
+			return m
+		}
+	}
+}
+
+// diagnostics reads messages until the next published diagnostics
+func (cl *client) diagnostics() []diagnostic {
+	for {
+		m := cl.read()
+		if m.Method == "textDocument/publishDiagnostics" {
+			var p publishDiagnosticsParams
+			err := json.Unmarshal(m.Params, &p)
+			cl.c.Assert(err, qt.IsNil)
+			return p.Diagnostics
+		}
+	}
+}
+
+func decode(c *qt.C, m message, v interface{}) {
+	c.Assert(m.Error, qt.IsNil)
+	err := json.Unmarshal(m.Result, v)
+	c.Assert(err, qt.IsNil)
+}
+
+func at(line, char int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     position{Line: line, Character: char},
+	}
+}
+
+func TestServe(t *testing.T) {
+	c := qt.New(t)
+	cl := newClient(c)
+
+	res := cl.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}})
+	var init struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	decode(c, res, &init)
+	c.Assert(init.Capabilities["hoverProvider"], qt.Equals, true)
+	cl.notify("initialized", map[string]interface{}{})
+
+	cl.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "bf", "version": 1, "text": "+[\n>é]]"},
+	})
+	diags := cl.diagnostics()
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Message, qt.Equals, "unmatched ]")
+	c.Assert(diags[0].Range.Start, qt.Equals, position{Line: 1, Character: 3})
+
+	src := "+ add\n[->\n [-<+>]\n  ]"
+	cl.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]string{{"text": src}},
+	})
+	c.Assert(cl.diagnostics(), qt.HasLen, 0)
+
+	t.Run("hover", func(t *testing.T) {
+		var h hover
+		decode(c, cl.request("textDocument/hover", at(1, 0)), &h)
+		c.Assert(h.Contents.Value, qt.Contains, "loop 2:1 to 4:3, nesting depth 0")
+		c.Assert(h.Contents.Value, qt.Contains, "pointer movement per iteration: +1")
+
+		decode(c, cl.request("textDocument/hover", at(2, 3)), &h)
+		c.Assert(h.Contents.Value, qt.Contains, "moves the data pointer to the left")
+		c.Assert(h.Contents.Value, qt.Contains, "nesting depth 2")
+
+		res := cl.request("textDocument/hover", at(0, 3))
+		c.Assert(string(res.Result), qt.Equals, "null")
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var loc location
+		decode(c, cl.request("textDocument/definition", at(1, 0)), &loc)
+		c.Assert(loc.Range.Start, qt.Equals, position{Line: 3, Character: 2})
+		decode(c, cl.request("textDocument/definition", at(2, 6)), &loc)
+		c.Assert(loc.Range.Start, qt.Equals, position{Line: 2, Character: 1})
+	})
+
+	t.Run("formatting", func(t *testing.T) {
+		var edits []textEdit
+		decode(c, cl.request("textDocument/formatting", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri},
+			"options":      map[string]interface{}{"tabSize": 2, "insertSpaces": true},
+		}), &edits)
+		c.Assert(edits, qt.HasLen, 1)
+		// the same as bf fmt
+		want, err := format.Source([]byte(src), format.Config{Indent: "  "})
+		c.Assert(err, qt.IsNil)
+		c.Assert(edits[0].NewText, qt.Equals, string(want))
+		c.Assert(edits[0].NewText, qt.Equals, "+ add\n[\n  ->[-<+>]\n]\n")
+	})
+
+	t.Run("folding", func(t *testing.T) {
+		var ranges []foldingRange
+		decode(c, cl.request("textDocument/foldingRange", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri},
+		}), &ranges)
+		c.Assert(ranges, qt.DeepEquals, []foldingRange{{StartLine: 1, EndLine: 2, Kind: "region"}})
+	})
+
+	t.Run("semantic tokens", func(t *testing.T) {
+		var tokens semanticTokens
+		decode(c, cl.request("textDocument/semanticTokens/full", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri},
+		}), &tokens)
+		c.Assert(tokens.Data[:15], qt.DeepEquals, []int{
+			0, 0, 1, tokenOperator, 0,
+			0, 2, 3, tokenComment, 0,
+			1, 0, 1, tokenKeyword, 0,
+		})
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		res := cl.request("textDocument/rename", at(0, 0))
+		c.Assert(res.Error, qt.Not(qt.IsNil))
+		c.Assert(res.Error.Code, qt.Equals, codeMethodNotFound)
+	})
+
+	cl.request("shutdown", nil)
+	cl.notify("exit", nil)
+	c.Assert(<-cl.done, qt.IsNil)
+}
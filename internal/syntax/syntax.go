@@ -0,0 +1,160 @@
+// Package syntax gives the interpreter and the tools built around it the commands and
+// loops of a BF source, as parsed by the ast package, keeping the offset of every node.
+// The comments and the characters that could not be parsed are left out.
+package syntax
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/thesoulless/bf/ast"
+)
+
+var (
+	ErrIllegalCharNul   = ast.ErrIllegalCharNul
+	ErrLoopDoesNotMatch = ast.ErrLoopDoesNotMatch
+)
+
+type (
+	// Error is an error found at a position of the source.
+	Error = ast.Error
+	// ErrorList is the list of errors found in a source, ordered by position.
+	ErrorList = ast.ErrorList
+)
+
+// Node is a command or a loop.
+type Node interface {
+	Pos() int // offset of the first character of the node
+	End() int // offset right after the last character of the node
+}
+
+// Command is a single command, other than a loop bracket.
+type Command struct {
+	Offset int
+	Op     byte
+}
+
+// Loop is a loop, with the offsets of its brackets.
+type Loop struct {
+	Open, Close int
+	Body        []Node
+}
+
+func (c *Command) Pos() int { return c.Offset }
+func (c *Command) End() int { return c.Offset + 1 }
+func (l *Loop) Pos() int    { return l.Open }
+func (l *Loop) End() int    { return l.Close + 1 }
+
+// File is a parsed source.
+type File struct {
+	Src   []byte
+	Nodes []Node
+	Lines []int // offsets of the first character of each line
+
+	file *ast.File
+}
+
+// Parse parses src with ast.Parse. It returns an ErrorList with an error for each
+// unmatched bracket and NUL character. The File is always returned, with the unmatched
+// brackets left out of it.
+func Parse(src []byte) (*File, error) {
+	af, err := ast.Parse(src)
+	f := &File{Src: src, Nodes: nodes(af.Nodes), Lines: af.Lines, file: af}
+	return f, err
+}
+
+// nodes returns the commands and the loops of the ast nodes.
+func nodes(ns []ast.Node) []Node {
+	var res []Node
+	for _, n := range ns {
+		switch n := n.(type) {
+		case *ast.Command:
+			res = append(res, &Command{Offset: int(n.At), Op: n.Op})
+		case *ast.Loop:
+			res = append(res, &Loop{Open: int(n.Open), Close: int(n.Close), Body: nodes(n.Body)})
+		}
+	}
+	return res
+}
+
+// Position returns the line and the column, both starting at 1, of offset.
+func (f *File) Position(offset int) (line, column int) {
+	return f.file.Position(ast.Pos(offset))
+}
+
+// Span returns the source range from the offset from to the offset to, both included, as
+// line:column-line:column, or as line:column when they are the same.
+func (f *File) Span(from, to int) string {
+	l1, c1 := f.Position(from)
+	if from == to {
+		return fmt.Sprintf("%d:%d", l1, c1)
+	}
+	l2, c2 := f.Position(to)
+	return fmt.Sprintf("%d:%d-%d:%d", l1, c1, l2, c2)
+}
+
+// Inspect calls fn for each node in depth-first order, with the loops enclosing the node,
+// the outermost first. If fn returns false, the children of the node are skipped.
+func (f *File) Inspect(fn func(n Node, loops []*Loop) bool) {
+	var walk func(nodes []Node, loops []*Loop)
+	walk = func(nodes []Node, loops []*Loop) {
+		for _, n := range nodes {
+			if !fn(n, loops) {
+				continue
+			}
+			if l, ok := n.(*Loop); ok {
+				walk(l.Body, append(loops, l))
+			}
+		}
+	}
+	walk(f.Nodes, nil)
+}
+
+// Enclosing returns the loops enclosing offset, the outermost first. The brackets of a
+// loop are part of it.
+func (f *File) Enclosing(offset int) []*Loop {
+	var loops []*Loop
+	nodes := f.Nodes
+	for {
+		i := sort.Search(len(nodes), func(i int) bool { return nodes[i].End() > offset })
+		if i == len(nodes) || nodes[i].Pos() > offset {
+			return loops
+		}
+
+		l, ok := nodes[i].(*Loop)
+		if !ok {
+			return loops
+		}
+		loops = append(loops, l)
+		nodes = l.Body
+	}
+}
+
+// Net returns the net data pointer movement of one iteration of the loop. It returns false
+// if it can not be known statically, which is when an inner loop does not have a zero
+// net movement itself.
+func (l *Loop) Net() (int, bool) {
+	return net(l.Body)
+}
+
+func net(nodes []Node) (int, bool) {
+	n := 0
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *Command:
+			switch node.Op {
+			case '>':
+				n++
+			case '<':
+				n--
+			}
+		case *Loop:
+			d, ok := node.Net()
+			if !ok || d != 0 {
+				return 0, false
+			}
+		}
+	}
+
+	return n, true
+}
@@ -0,0 +1,111 @@
+package syntax
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParse(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("tree", func(t *testing.T) {
+		src := []byte("+ add\n[->[<]>]")
+		f, err := Parse(src)
+		c.Assert(err, qt.IsNil)
+		c.Assert(f.Lines, qt.DeepEquals, []int{0, 6})
+		c.Assert(f.Nodes, qt.HasLen, 2)
+
+		l := f.Nodes[1].(*Loop)
+		c.Assert(l.Open, qt.Equals, 6)
+		c.Assert(l.Close, qt.Equals, 13)
+		c.Assert(l.Body, qt.HasLen, 4)
+
+		line, col := f.Position(8)
+		c.Assert([]int{line, col}, qt.DeepEquals, []int{2, 3})
+
+		var ops []byte
+		f.Inspect(func(n Node, loops []*Loop) bool {
+			if cmd, ok := n.(*Command); ok {
+				ops = append(ops, cmd.Op)
+			} else {
+				ops = append(ops, '0'+byte(len(loops)))
+			}
+			return true
+		})
+		c.Assert(string(ops), qt.Equals, "+0->1<>")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		src := []byte("][+\x00[[-]")
+		f, err := Parse(src)
+		c.Assert(err, qt.Not(qt.IsNil))
+
+		var errs ErrorList
+		c.Assert(errors.As(err, &errs), qt.IsTrue)
+		c.Assert(errs, qt.HasLen, 4)
+		for i, want := range []*Error{
+			{Pos: 0, Err: ErrLoopDoesNotMatch},
+			{Pos: 1, Err: ErrLoopDoesNotMatch},
+			{Pos: 3, Err: ErrIllegalCharNul},
+			{Pos: 4, Err: ErrLoopDoesNotMatch},
+		} {
+			c.Assert(errs[i].Pos, qt.Equals, want.Pos)
+			c.Assert(errs[i].Err, qt.Equals, want.Err)
+		}
+		c.Assert(errors.Is(err, ErrLoopDoesNotMatch), qt.IsTrue)
+
+		// the unmatched bracket is left out, and its body is kept
+		c.Assert(f.Nodes, qt.HasLen, 2)
+		c.Assert(f.Nodes[1].(*Loop).Open, qt.Equals, 5)
+	})
+}
+
+func TestFile_Span(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := Parse([]byte("+\n[-]"))
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(f.Span(0, 0), qt.Equals, "1:1")
+	c.Assert(f.Span(2, 4), qt.Equals, "2:1-2:3")
+	c.Assert(f.Span(0, 3), qt.Equals, "1:1-2:2")
+}
+
+func TestFile_Enclosing(t *testing.T) {
+	c := qt.New(t)
+
+	f, err := Parse([]byte("+[>[-]<]+"))
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(f.Enclosing(0), qt.HasLen, 0)
+	c.Assert(f.Enclosing(1), qt.HasLen, 1)
+	c.Assert(f.Enclosing(4), qt.HasLen, 2)
+	c.Assert(f.Enclosing(5)[1].Open, qt.Equals, 3)
+	c.Assert(f.Enclosing(7), qt.HasLen, 1)
+	c.Assert(f.Enclosing(8), qt.HasLen, 0)
+}
+
+func TestLoop_Net(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		src  string
+		net  int
+		know bool
+	}{
+		{"[->+<]", 0, true},
+		{"[>>]", 2, true},
+		{"[<[-]]", -1, true},
+		{"[>[>]<]", 0, false},
+	}
+	for _, tt := range tests {
+		f, err := Parse([]byte(tt.src))
+		c.Assert(err, qt.IsNil)
+
+		n, ok := f.Nodes[0].(*Loop).Net()
+		c.Assert(ok, qt.Equals, tt.know, qt.Commentf(tt.src))
+		c.Assert(n, qt.Equals, tt.net, qt.Commentf(tt.src))
+	}
+}
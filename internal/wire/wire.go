@@ -0,0 +1,56 @@
+// Package wire reads and writes JSON messages framed by a Content-Length header, as
+// used by the Debug Adapter Protocol and the Language Server Protocol.
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+var errNoContentLength = errors.New("missing Content-Length header")
+
+// Read reads a message from r, and unmarshals it into v. It returns io.EOF if r
+// is closed before a message starts.
+func Read(r *bufio.Reader, v interface{}) error {
+	h, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(h) == 0 {
+			return io.EOF
+		}
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+
+	cl := h.Get("Content-Length")
+	if cl == "" {
+		return errNoContentLength
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(cl))
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid Content-Length %q", cl)
+	}
+
+	body := make([]byte, n)
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// Write marshals v, and writes it to w as a message.
+func Write(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
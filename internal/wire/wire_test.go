@@ -0,0 +1,37 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestReadWrite(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	err := Write(&buf, map[string]int{"seq": 1})
+	c.Assert(err, qt.IsNil)
+	err = Write(&buf, map[string]int{"seq": 2})
+	c.Assert(err, qt.IsNil)
+	c.Assert(strings.HasPrefix(buf.String(), "Content-Length: 9\r\n\r\n{\"seq\":1}"), qt.IsTrue)
+
+	r := bufio.NewReader(&buf)
+	for _, want := range []int{1, 2} {
+		var m map[string]int
+		err = Read(r, &m)
+		c.Assert(err, qt.IsNil)
+		c.Assert(m["seq"], qt.Equals, want)
+	}
+
+	var m map[string]int
+	err = Read(r, &m)
+	c.Assert(err, qt.Equals, io.EOF)
+
+	err = Read(bufio.NewReader(strings.NewReader("Content-Type: x\r\n\r\n{}")), &m)
+	c.Assert(err, qt.Equals, errNoContentLength)
+}
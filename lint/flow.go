@@ -0,0 +1,149 @@
+package lint
+
+import "github.com/thesoulless/bf/internal/syntax"
+
+// value is what is known of a cell.
+type value struct {
+	v     int32
+	known bool
+}
+
+// state is what is known of the machine at a point of the program. Cells are keyed by
+// their offset from a base cell, which is the first cell when abs is set.
+type state struct {
+	abs   bool
+	ptr   int
+	fresh bool // cells not in vals were never changed, so they are zero
+	vals  map[int]value
+}
+
+func (s *state) get(i int) value {
+	if v, ok := s.vals[i]; ok {
+		return v
+	}
+	if s.fresh {
+		return value{known: true}
+	}
+	return value{}
+}
+
+// forget drops what is known of the cells.
+func (s *state) forget() {
+	s.fresh = false
+	s.vals = make(map[int]value)
+}
+
+// outcome is how running a list of nodes ends.
+type outcome int
+
+const (
+	passes outcome = iota // runs past its end
+	stuck                 // loops forever
+	fails                 // stops with an error
+)
+
+// flow follows the paths of the program from its start, reporting the loops that never
+// run, the code after loops that never end, the pointer moves left of the first cell, and
+// the loops that move the pointer on each iteration.
+func (l *linter) flow() {
+	s := &state{abs: true, fresh: true, vals: make(map[int]value)}
+	l.walk(l.f.Nodes, s)
+}
+
+func (l *linter) walk(nodes []syntax.Node, s *state) outcome {
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case *syntax.Command:
+			switch n.Op {
+			case '>':
+				s.ptr++
+			case '<':
+				if s.abs && s.ptr == 0 {
+					l.report("negative-pointer", n.Offset, n.Offset, "the pointer moves left of the first cell")
+					return fails
+				}
+				s.ptr--
+			case '+', '-':
+				v := s.get(s.ptr)
+				if n.Op == '+' {
+					v.v++
+				} else {
+					v.v--
+				}
+				s.vals[s.ptr] = v
+			case ',':
+				s.vals[s.ptr] = value{}
+			}
+		case *syntax.Loop:
+			switch l.loop(n, s) {
+			case stuck:
+				if rest := nodes[i+1:]; len(rest) > 0 {
+					l.report("unreachable", rest[0].Pos(), rest[len(rest)-1].End()-1,
+						"unreachable code: the loop at %s never ends", l.pos(n.Open))
+				}
+				return stuck
+			case fails:
+				return fails
+			}
+		}
+	}
+
+	return passes
+}
+
+// loop follows the loop lp reached in state s, and updates s to the state after it.
+func (l *linter) loop(lp *syntax.Loop, s *state) outcome {
+	v := s.get(s.ptr)
+	if v.known && v.v == 0 {
+		l.report("dead-loop", lp.Open, lp.Close, "the loop never runs: the current cell is always zero here")
+		return passes
+	}
+
+	net, ok := lp.Net()
+	// the body is followed once, for the first iteration, knowing nothing of the cells,
+	// since they may differ on later iterations
+	body := &state{abs: s.abs, ptr: s.ptr, vals: make(map[int]value)}
+	out := l.walk(lp.Body, body)
+	if v.known {
+		// the loop is always entered
+		if out != passes {
+			return out
+		}
+		if ok && net == 0 && !writes(lp.Body, 0) {
+			return stuck
+		}
+	}
+
+	if !ok || net != 0 {
+		s.abs = false
+		s.ptr = 0
+	}
+	s.forget()
+	s.vals[s.ptr] = value{known: true}
+	return passes
+}
+
+// writes reports whether nodes may change the cell at offset off from the current cell.
+func writes(nodes []syntax.Node, off int) bool {
+	d := 0
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *syntax.Command:
+			switch n.Op {
+			case '>':
+				d++
+			case '<':
+				d--
+			case '+', '-', ',':
+				if d == off {
+					return true
+				}
+			}
+		case *syntax.Loop:
+			if net, ok := n.Net(); !ok || net != 0 || writes(n.Body, off-d) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteText writes the diagnostics to w, one per line in the
+// file:line:column: severity: message (rule) form.
+func WriteText(w io.Writer, diags []Diagnostic) error {
+	for _, d := range diags {
+		_, err := fmt.Fprintln(w, d)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the diagnostics to w as a JSON array.
+func WriteJSON(w io.Writer, diags []Diagnostic) error {
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diags)
+}
+
+// SARIF types, the part of the SARIF 2.1.0 log format the linter writes.
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool       sarifTool     `json:"tool"`
+		ColumnKind string        `json:"columnKind"`
+		Results    []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string       `json:"id"`
+		ShortDescription     sarifMessage `json:"shortDescription"`
+		DefaultConfiguration struct {
+			Level Severity `json:"level"`
+		} `json:"defaultConfiguration"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     Severity        `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region sarifRegion `json:"region"`
+		} `json:"physicalLocation"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+		EndLine     int `json:"endLine"`
+		EndColumn   int `json:"endColumn"` // exclusive
+		ByteOffset  int `json:"byteOffset"`
+		ByteLength  int `json:"byteLength"`
+	}
+)
+
+// WriteSARIF writes the diagnostics to w as a SARIF 2.1.0 log, for code scanning tools.
+// Columns count bytes, which are code points in ASCII sources.
+func WriteSARIF(w io.Writer, diags []Diagnostic) error {
+	driver := sarifDriver{
+		Name:           "bf lint",
+		InformationURI: "https://github.com/thesoulless/bf",
+	}
+	index := make(map[string]int)
+	for i, r := range Rules {
+		sr := sarifRule{ID: r.Name, ShortDescription: sarifMessage{r.Description}}
+		sr.DefaultConfiguration.Level = r.Severity
+		driver.Rules = append(driver.Rules, sr)
+		index[r.Name] = i
+	}
+
+	results := []sarifResult{}
+	for _, d := range diags {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = d.File
+		loc.PhysicalLocation.Region = sarifRegion{
+			StartLine:   d.Pos.Line,
+			StartColumn: d.Pos.Column,
+			EndLine:     d.End.Line,
+			EndColumn:   d.End.Column + 1,
+			ByteOffset:  d.Pos.Offset,
+			ByteLength:  d.End.Offset - d.Pos.Offset + 1,
+		}
+		results = append(results, sarifResult{
+			RuleID:    d.Rule,
+			RuleIndex: index[d.Rule],
+			Level:     d.Severity,
+			Message:   sarifMessage{d.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool:       sarifTool{Driver: driver},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	})
+}
@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWriteText(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	err := WriteText(&buf, Lint("p.bf", []byte("[-]+-")))
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `p.bf:1:1: warning: the loop never runs: the current cell is always zero here (dead-loop)
+p.bf:1:4: warning: "+-" cancels out (cancel)
+`)
+}
+
+func TestWriteJSON(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	err := WriteJSON(&buf, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, "[]\n")
+
+	buf.Reset()
+	diags := Lint("p.bf", []byte(",\n+-"))
+	err = WriteJSON(&buf, diags)
+	c.Assert(err, qt.IsNil)
+
+	var got []Diagnostic
+	err = json.Unmarshal(buf.Bytes(), &got)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, diags)
+	c.Assert(buf.String(), qt.Contains, `"pos": {
+      "offset": 2,
+      "line": 2,
+      "column": 1
+    }`)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	err := WriteSARIF(&buf, Lint("p.bf", []byte("+[.]\n>+")))
+	c.Assert(err, qt.IsNil)
+
+	var log sarifLog
+	err = json.Unmarshal(buf.Bytes(), &log)
+	c.Assert(err, qt.IsNil)
+	c.Assert(log.Version, qt.Equals, "2.1.0")
+	c.Assert(log.Runs, qt.HasLen, 1)
+
+	run := log.Runs[0]
+	c.Assert(run.Tool.Driver.Rules, qt.HasLen, len(Rules))
+	c.Assert(run.Results, qt.HasLen, 1)
+	res := run.Results[0]
+	c.Assert(res.RuleID, qt.Equals, "unreachable")
+	c.Assert(run.Tool.Driver.Rules[res.RuleIndex].ID, qt.Equals, "unreachable")
+	c.Assert(res.Level, qt.Equals, Warning)
+	loc := res.Locations[0].PhysicalLocation
+	c.Assert(loc.ArtifactLocation.URI, qt.Equals, "p.bf")
+	c.Assert(loc.Region, qt.Equals, sarifRegion{
+		StartLine: 2, StartColumn: 1, EndLine: 2, EndColumn: 3, ByteOffset: 5, ByteLength: 2,
+	})
+}
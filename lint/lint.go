@@ -0,0 +1,260 @@
+// Package lint finds likely mistakes in BF sources: loops that can never run or never end,
+// commands that cancel each other out, pointer moves left of the first cell, and command
+// characters hiding in comments.
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// Severity is how serious a diagnostic is. The values are the SARIF levels.
+type Severity string
+
+const (
+	Error   Severity = "error"   // the program fails, or does not parse
+	Warning Severity = "warning" // the program likely does not do what was meant
+	Note    Severity = "note"    // the code may be intended, but is worth a look
+)
+
+// Rule is a check of the linter.
+type Rule struct {
+	Name        string
+	Severity    Severity
+	Description string
+}
+
+// Rules are the checks run by Lint.
+var Rules = []Rule{
+	{"syntax", Error, "The source does not parse: a bracket is unmatched, or there is a NUL character."},
+	{"negative-pointer", Error, "A path moves the data pointer left of the first cell."},
+	{"dead-loop", Warning, "A loop can never run, since the current cell is always zero when it is reached, e.g. at the start of the program or right after another loop."},
+	{"unreachable", Warning, "Commands follow a loop that never ends once it is reached."},
+	{"cancel", Warning, "Adjacent commands cancel each other out, like \"+-\" or \"<>\"."},
+	{"comment-command", Warning, "A command character is part of the text of a comment, like the '.' ending a sentence or the '-' of a hyphenated word."},
+	{"unbalanced-loop", Note, "A loop moves the data pointer on every iteration, so the cells it works on change."},
+}
+
+// rule returns the rule named name.
+func rule(name string) Rule {
+	for _, r := range Rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	panic("lint: unknown rule " + name)
+}
+
+// Position is a location in a source.
+type Position struct {
+	Offset int `json:"offset"` // byte offset, starting at 0
+	Line   int `json:"line"`   // line number, starting at 1
+	Column int `json:"column"` // column number, starting at 1 (byte count)
+}
+
+// String returns the position in the line:column form.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Diagnostic is a problem found by a rule, from Pos to End, both inclusive.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Pos      Position `json:"pos"`
+	End      Position `json:"end"`
+}
+
+// String returns the diagnostic in the file:line:column: severity: message (rule) form.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%s: %s: %s (%s)", d.File, d.Pos, d.Severity, d.Message, d.Rule)
+}
+
+// linter holds the diagnostics found in a file.
+type linter struct {
+	name  string
+	f     *syntax.File
+	diags []Diagnostic
+}
+
+// Lint checks src with every rule, and returns the diagnostics ordered by position. file is
+// the name of src given in the diagnostics. The flow rules are skipped when src does not
+// parse.
+func Lint(file string, src []byte) []Diagnostic {
+	f, err := syntax.Parse(src)
+	l := &linter{name: file, f: f}
+
+	if err != nil {
+		var errs syntax.ErrorList
+		if !errors.As(err, &errs) {
+			errs = syntax.ErrorList{{Err: err}}
+		}
+		for _, e := range errs {
+			msg := e.Err.Error()
+			if e.Err == syntax.ErrLoopDoesNotMatch {
+				msg = fmt.Sprintf("unmatched %c", src[int(e.Pos)])
+			}
+			l.report("syntax", int(e.Pos), int(e.Pos), msg)
+		}
+	} else {
+		l.flow()
+	}
+	l.unbalanced()
+	l.cancel()
+	l.comments()
+
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		return l.diags[i].Pos.Offset < l.diags[j].Pos.Offset
+	})
+	return l.diags
+}
+
+func (l *linter) pos(off int) Position {
+	line, col := l.f.Position(off)
+	return Position{Offset: off, Line: line, Column: col}
+}
+
+// report adds a diagnostic of the rule name, for the source from offset pos to end.
+func (l *linter) report(name string, pos, end int, format string, args ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{
+		File:     l.name,
+		Rule:     name,
+		Severity: rule(name).Severity,
+		Message:  fmt.Sprintf(format, args...),
+		Pos:      l.pos(pos),
+		End:      l.pos(end),
+	})
+}
+
+// unbalanced reports the loops that move the pointer on each iteration.
+func (l *linter) unbalanced() {
+	l.f.Inspect(func(n syntax.Node, _ []*syntax.Loop) bool {
+		if lp, ok := n.(*syntax.Loop); ok {
+			if net, ok := lp.Net(); ok && net != 0 {
+				l.report("unbalanced-loop", lp.Open, lp.Close, "the loop moves the pointer by %+d on each iteration", net)
+			}
+		}
+		return true
+	})
+}
+
+// cancel reports runs of adjacent commands that cancel each other out, wholly or in part.
+func (l *linter) cancel() {
+	var check func(nodes []syntax.Node)
+	check = func(nodes []syntax.Node) {
+		var run []*syntax.Command
+		flush := func() {
+			l.cancelRun(run)
+			run = nil
+		}
+
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *syntax.Command:
+				if len(run) > 0 && pair(run[0].Op) != pair(n.Op) {
+					flush()
+				}
+				if pair(n.Op) != 0 {
+					run = append(run, n)
+				}
+			case *syntax.Loop:
+				flush()
+				check(n.Body)
+			}
+		}
+		flush()
+	}
+	check(l.f.Nodes)
+}
+
+// pair returns the first command of the pair of opposite commands op is in, or zero.
+func pair(op byte) byte {
+	switch op {
+	case '+', '-':
+		return '+'
+	case '>', '<':
+		return '>'
+	}
+	return 0
+}
+
+// cancelRun reports run, a run of '+' and '-' or of '>' and '<', if it has both commands.
+func (l *linter) cancelRun(run []*syntax.Command) {
+	if len(run) < 2 {
+		return
+	}
+
+	var ops strings.Builder
+	n := 0
+	for _, c := range run {
+		ops.WriteByte(c.Op)
+		if c.Op == run[0].Op {
+			n++
+		} else {
+			n--
+		}
+	}
+	if n == len(run) {
+		return
+	}
+
+	first, last := run[0], run[len(run)-1]
+	if n == 0 {
+		l.report("cancel", first.Offset, last.Offset, "%q cancels out", ops.String())
+		return
+	}
+	op := run[0].Op
+	if n < 0 {
+		n = -n
+		op = opposite(op)
+	}
+	l.report("cancel", first.Offset, last.Offset, "%q is the same as %q", ops.String(), strings.Repeat(string(op), n))
+}
+
+func opposite(op byte) byte {
+	switch op {
+	case '+':
+		return '-'
+	case '-':
+		return '+'
+	case '>':
+		return '<'
+	}
+	return '>'
+}
+
+// comments reports the '.', ',', '-' and '+' right after a letter, which are most likely
+// punctuation of the text of a comment.
+func (l *linter) comments() {
+	src := l.f.Src
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '.', ',', '-', '+':
+		default:
+			continue
+		}
+		if !isLetter(src[i-1]) {
+			continue
+		}
+
+		start := i - 1
+		for start > 0 && isLetter(src[start-1]) {
+			start--
+		}
+		end := i + 1
+		for end < len(src) && isLetter(src[end]) {
+			end++
+		}
+		l.report("comment-command", i, i, "%q in the comment %q is a command", src[i], src[start:end])
+	}
+}
+
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
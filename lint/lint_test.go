@@ -0,0 +1,81 @@
+package lint
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLint(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"clean", "++[>+<-]>.", nil},
+		{"syntax", "+]", []string{
+			"p.bf:1:2: error: unmatched ] (syntax)",
+		}},
+		{"dead loop at start", "[-]+", []string{
+			"p.bf:1:1: warning: the loop never runs: the current cell is always zero here (dead-loop)",
+		}},
+		{"dead loop after a loop", "+[-]\n[>+<-]", []string{
+			"p.bf:2:1: warning: the loop never runs: the current cell is always zero here (dead-loop)",
+		}},
+		{"zeroed cell", "+>+<-[.]", []string{
+			"p.bf:1:6: warning: the loop never runs: the current cell is always zero here (dead-loop)",
+		}},
+		{"live loop after input", ",[.,]", nil},
+		{"unreachable", "+[.]>+.", []string{
+			"p.bf:1:5: warning: unreachable code: the loop at 1:2 never ends (unreachable)",
+		}},
+		{"unreachable in a loop", ",[[-]+[]>.]", []string{
+			"p.bf:1:2: note: the loop moves the pointer by +1 on each iteration (unbalanced-loop)",
+			"p.bf:1:9: warning: unreachable code: the loop at 1:7 never ends (unreachable)",
+		}},
+		{"cancel", ">++-+>><<<", []string{
+			"p.bf:1:2: warning: \"++-+\" is the same as \"++\" (cancel)",
+			"p.bf:1:6: warning: \">><<<\" is the same as \"<\" (cancel)",
+		}},
+		{"cancel out", "+[-+]", []string{
+			"p.bf:1:3: warning: \"-+\" cancels out (cancel)",
+		}},
+		{"unbalanced loop", "+[>]", []string{
+			"p.bf:1:2: note: the loop moves the pointer by +1 on each iteration (unbalanced-loop)",
+		}},
+		{"negative pointer", "+[->+<]>[-<<+>>]", []string{
+			"p.bf:1:12: error: the pointer moves left of the first cell (negative-pointer)",
+		}},
+		{"negative pointer after an unbalanced loop", ",[>,]<<", []string{
+			"p.bf:1:2: note: the loop moves the pointer by +1 on each iteration (unbalanced-loop)",
+		}},
+		{"comment", "prints hello, world.\n+.", []string{
+			"p.bf:1:13: warning: ',' in the comment \"hello,\" is a command (comment-command)",
+			"p.bf:1:20: warning: '.' in the comment \"world.\" is a command (comment-command)",
+		}},
+		{"hyphen", "a well-known trick ,.", []string{
+			"p.bf:1:7: warning: '-' in the comment \"well-known\" is a command (comment-command)",
+		}},
+	}
+
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			var got []string
+			for _, d := range Lint("p.bf", []byte(test.src)) {
+				got = append(got, d.String())
+			}
+			c.Assert(got, qt.DeepEquals, test.want)
+		})
+	}
+}
+
+func TestLint_Range(t *testing.T) {
+	c := qt.New(t)
+
+	diags := Lint("p.bf", []byte("+[.]\n>+\n."))
+	c.Assert(diags, qt.HasLen, 1)
+	c.Assert(diags[0].Pos, qt.Equals, Position{Offset: 5, Line: 2, Column: 1})
+	c.Assert(diags[0].End, qt.Equals, Position{Offset: 8, Line: 3, Column: 1})
+}
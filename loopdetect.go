@@ -0,0 +1,131 @@
+package bf
+
+import "errors"
+
+// ErrInfiniteLoop is returned by WithLoopDetection when a loop can never end.
+var ErrInfiniteLoop = errors.New("infinite loop")
+
+// WithLoopDetection stops the run with ErrInfiniteLoop, at the position of the loop, when
+// the machine is provably stuck in it: a loop with no commands is entered, e.g. "[]" on a
+// non-zero cell, or the data pointer, the cells and the loop being run repeat exactly at
+// the start of an iteration, with no input read in between.
+//
+// The state is checked on a doubling schedule, so a repeating cycle is found within about
+// twice its length, keeping a single copy of the cells. Programs that never repeat a state,
+// e.g. ones counting forever, are not detected.
+func WithLoopDetection() Option {
+	return func(b *BF) {
+		b.cycles = &cycles{}
+		b.cycles.reset(b)
+	}
+}
+
+// cycles finds repeating states at the start of loop iterations, with Brent's algorithm.
+type cycles struct {
+	hash uint64 // hash of the cells, kept up to date as they change
+
+	// the saved state
+	saved  bool
+	pc     int
+	ptr    int
+	sum    uint64
+	tape   []int32
+	power  int // heads checked before the state is saved again
+	checks int // heads checked since the state was saved
+}
+
+// reset forgets the saved state and hashes the cells again.
+func (d *cycles) reset(b *BF) {
+	d.hash = 0
+	for i, v := range b.arr {
+		d.hash += cellHash(i, v)
+	}
+	d.saved = false
+	d.power = 1
+	d.checks = 0
+}
+
+// cellHash hashes a cell so the hash of the cells is the sum of the hashes of each cell,
+// and zero cells, like the ones added when the backing array grows, do not change it.
+func cellHash(i int, v int32) uint64 {
+	if v == 0 {
+		return 0
+	}
+	// splitmix64 finalizer
+	x := uint64(i)<<32 | uint64(uint32(v))
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// update follows the command ch at offset at, run with the data pointer at ptr and the
+// current cell holding old. It returns ErrInfiniteLoop if the command started an
+// iteration of a loop that can never end.
+func (d *cycles) update(b *BF, at int, ch rune, ptr int, old int32) error {
+	switch ch {
+	case '+', '-':
+		d.hash += cellHash(ptr, b.arr[ptr]) - cellHash(ptr, old)
+		return nil
+	case '[', ']':
+		open := at
+		if ch == ']' {
+			open = b.jump[at]
+		}
+		if b.pc != open+1 {
+			// the loop was skipped or ended
+			return nil
+		}
+		if b.peek(b.pc) == b.jump[open] {
+			// nothing in the loop can change the current cell
+			return ErrInfiniteLoop
+		}
+		return d.check(b)
+	case '>', '<', '.':
+		return nil
+	case '#':
+		if b.debug != nil {
+			return nil
+		}
+	}
+
+	// input, or a custom command that may change any cell
+	d.reset(b)
+	return nil
+}
+
+// check compares the state at the start of a loop iteration with the saved state.
+func (d *cycles) check(b *BF) error {
+	if d.saved && d.pc == b.pc && d.ptr == b.c && d.sum == d.hash && d.same(b.arr) {
+		return ErrInfiniteLoop
+	}
+
+	d.checks++
+	if !d.saved || d.checks == d.power {
+		d.saved = true
+		d.pc, d.ptr, d.sum = b.pc, b.c, d.hash
+		d.tape = append(d.tape[:0], b.arr...)
+		d.power *= 2
+		d.checks = 0
+	}
+	return nil
+}
+
+// same reports whether the saved cells equal arr, where missing cells are zero.
+func (d *cycles) same(arr []int32) bool {
+	for i := 0; i < len(arr) || i < len(d.tape); i++ {
+		var a, t int32
+		if i < len(arr) {
+			a = arr[i]
+		}
+		if i < len(d.tape) {
+			t = d.tape[i]
+		}
+		if a != t {
+			return false
+		}
+	}
+	return true
+}
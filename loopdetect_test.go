@@ -0,0 +1,62 @@
+package bf
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWithLoopDetection(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name  string
+		src   string
+		input string
+		err   string
+	}{
+		{"empty loop", "+[]", "", "infinite loop at 1:2 \\(line:column\\)"},
+		{"empty loop with comments", "+[ no commands ]", "", "infinite loop at 1:2 \\(line:column\\)"},
+		{"skipped empty loop", "[]+", "", ""},
+		{"repeating cell", "+[-+]", "", "infinite loop at 1:2 \\(line:column\\)"},
+		{"inner loop", "+\n[>+[-+]<]", "", "infinite loop at 2:4 \\(line:column\\)"},
+		{"outer loop", "++[>+[-]<]", "", "infinite loop at 1:3 \\(line:column\\)"},
+		{"moving pointer", "+[>+<->-<+]", "", "infinite loop at 1:2 \\(line:column\\)"},
+		{"ending loop", "+++[>++[>+<-]<-]", "", ""},
+		{"input in between", "+[,]", "1\n1\n0\n", ""},
+		{"input before", ",[]", "1\n", "infinite loop at 1:2 \\(line:column\\)"},
+	}
+
+	for _, test := range tests {
+		c.Run(test.name, func(c *qt.C) {
+			bfi, err := New(strings.NewReader(test.src), io.Discard, strings.NewReader(test.input), WithLoopDetection())
+			c.Assert(err, qt.IsNil)
+			_, err = bfi.Continue()
+			if test.err == "" {
+				c.Assert(err, qt.Equals, io.EOF)
+				return
+			}
+			c.Assert(err, qt.ErrorIs, ErrInfiniteLoop)
+			c.Assert(err, qt.ErrorMatches, test.err)
+		})
+	}
+}
+
+func TestWithLoopDetection_StepBack(t *testing.T) {
+	c := qt.New(t)
+
+	bfi, err := New(strings.NewReader("+[-+]"), io.Discard, nil, WithLoopDetection(), WithHistory(0))
+	c.Assert(err, qt.IsNil)
+	for i := 0; i < 4; i++ {
+		_, err = bfi.Step()
+		c.Assert(err, qt.IsNil)
+	}
+	_, err = bfi.StepBack()
+	c.Assert(err, qt.IsNil)
+
+	err = bfi.Exec()
+	c.Assert(err, qt.ErrorIs, ErrInfiniteLoop)
+	c.Assert(bfi.Cell(0), qt.Equals, int32(1))
+}
@@ -0,0 +1,154 @@
+// Package minify makes BF programs smaller without changing what they do. It drops every
+// character that is not a command, the commands that cancel each other out, like +- and
+// ><, and the loops that can never run because their cell is always zero when they are
+// reached, like a loop at the start of the program or right after another loop. The
+// loops that never run are the zero-loop findings of the analysis package. They are all
+// kept when dropping them would leave a '<' that bf rejects before the program runs.
+//
+// Only the eight default commands are kept: custom commands and '#' are dropped as
+// comments.
+package minify
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/thesoulless/bf"
+	"github.com/thesoulless/bf/analysis"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// ErrNoOp is returned for a program that does nothing, which minifies to no command at
+// all. bf does not run an empty program.
+var ErrNoOp = errors.New("the program does nothing")
+
+// cells is the machine of bf, with int32 cells.
+var cells = analysis.Config{Bits: 32, Signed: true}
+
+// Result is a minified program.
+type Result struct {
+	Src []byte
+	// Offsets holds, for each command of Src, the offset in the original source of the
+	// command it comes from.
+	Offsets []int
+}
+
+// Minify minifies src. It returns an error if the loops of src do not match, or it holds
+// a NUL character, and ErrNoOp if no command is left.
+func Minify(src []byte) (*Result, error) {
+	f, err := syntax.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	a, err := analysis.Analyze(src, cells)
+	if err != nil {
+		return nil, err
+	}
+
+	dead := make(map[int]bool)
+	for _, f := range a.Findings {
+		if f.Kind == analysis.ZeroLoop {
+			dead[f.Pos.Offset] = true
+		}
+	}
+
+	r, err := minify(f, dead)
+	if err == nil && !valid(r.Src) && valid(src) {
+		// a '<' left of the first cell, which bf only finds at run time in the original
+		// program, is found before it runs once the loops that never run are dropped
+		r, err = minify(f, nil)
+	}
+	return r, err
+}
+
+// minify minifies f, dropping the loops of dead.
+func minify(f *syntax.File, dead map[int]bool) (*Result, error) {
+	m := &minifier{dead: dead}
+	m.list(f.Nodes, &state{abs: true})
+	if len(m.out) == 0 {
+		return nil, ErrNoOp
+	}
+
+	r := &Result{}
+	for _, c := range m.out {
+		r.Src = append(r.Src, c.op)
+		r.Offsets = append(r.Offsets, c.offset)
+	}
+	return r, nil
+}
+
+// valid reports whether src is a program that bf runs.
+func valid(src []byte) bool {
+	_, err := bf.New(bytes.NewReader(src), io.Discard, strings.NewReader(""))
+	return err == nil
+}
+
+// state is what is known of the data pointer at a point of the program. ptr is the index
+// of the current cell if abs, and it is unknown otherwise.
+type state struct {
+	ptr int
+	abs bool
+}
+
+// command is a command of the minified program.
+type command struct {
+	op     byte
+	offset int
+	// onTape is set for a '<' that is known to leave the pointer on the tape
+	onTape bool
+}
+
+// minifier builds the minified commands.
+type minifier struct {
+	out  []command
+	dead map[int]bool // loops that never run, by the offset of their '['
+}
+
+// list minifies nodes, from the state st.
+func (m *minifier) list(nodes []syntax.Node, st *state) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *syntax.Command:
+			m.command(n, st)
+		case *syntax.Loop:
+			m.loop(n, st)
+		}
+	}
+}
+
+func (m *minifier) command(c *syntax.Command, st *state) {
+	onTape := false
+	switch c.Op {
+	case '>':
+		st.ptr++
+	case '<':
+		onTape = st.abs && st.ptr >= 1
+		st.ptr--
+	}
+
+	// pairs that cancel out: +- -+ >< and <> when the '<' leaves the pointer on the tape
+	if n := len(m.out); n > 0 {
+		top := m.out[n-1]
+		switch {
+		case top.op == '+' && c.Op == '-', top.op == '-' && c.Op == '+',
+			top.op == '>' && c.Op == '<', top.op == '<' && c.Op == '>' && top.onTape:
+			m.out = m.out[:n-1]
+			return
+		}
+	}
+	m.out = append(m.out, command{op: c.Op, offset: c.Offset, onTape: onTape})
+}
+
+func (m *minifier) loop(l *syntax.Loop, st *state) {
+	if m.dead[l.Open] {
+		return
+	}
+
+	d, ok := l.Net()
+	st.abs = st.abs && ok && d == 0
+	m.out = append(m.out, command{op: '[', offset: l.Open})
+	m.list(l.Body, &state{ptr: st.ptr, abs: st.abs})
+	m.out = append(m.out, command{op: ']', offset: l.Close})
+}
@@ -0,0 +1,75 @@
+package minify
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"comments", "add 2 +\n+ and print .", "++."},
+		{"cancel", "+++-->><<><+-.", "+."},
+		{"cancel left", ">+<>.", ">+."},
+		{"left of the first cell", "+[<>-]", "+[<>-]"},
+		{"cancel nested", "+>+-<.", "+."},
+		{"leading loop", "[comment, with stuff.]+.", "+."},
+		{"after a loop", ",[-][->+<]>[.]+.", ",[-]>+."},
+		{"after an input", ",[.]", ",[.]"},
+		{"known cell", "++>--<[->+<]>+[-<->]", "++>--<[->+<]>+[-<->]"},
+		{"inside loops", ",[>[-][.]+<-]", ",[>[-]+<-]"},
+		{"unknown pointer", "+[<]<>.", "+[<]<>."},
+		{"known pointer", "+[>+<-]>>+<<>", "+[>+<-]>>+<"},
+		{"custom commands", "+#-!.", "."},
+		// the '<' fails when the program runs, which bf finds before it runs once the
+		// first loop is dropped, so no loop is dropped
+		{"failing", "[<].<[.]", "[<].<[.]"},
+		{"failing after a loop", "[-]<", "[-]<"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			r, err := Minify([]byte(test.src))
+			c.Assert(err, qt.IsNil)
+			c.Assert(string(r.Src), qt.Equals, test.want)
+			c.Assert(r.Offsets, qt.HasLen, len(r.Src))
+			for i, off := range r.Offsets {
+				c.Assert(test.src[off], qt.Equals, r.Src[i])
+			}
+		})
+	}
+}
+
+func TestMinify_NoOp(t *testing.T) {
+	c := qt.New(t)
+
+	for _, src := range []string{"+->[.]<[-]", "+#-!", "[-] comment"} {
+		_, err := Minify([]byte(src))
+		c.Assert(err, qt.Equals, ErrNoOp, qt.Commentf(src))
+	}
+}
+
+func TestMinify_Offsets(t *testing.T) {
+	c := qt.New(t)
+
+	r, err := Minify([]byte("+ +-\n[-]"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(r.Src), qt.Equals, "+[-]")
+	c.Assert(r.Offsets, qt.DeepEquals, []int{0, 5, 6, 7})
+}
+
+func TestMinify_Error(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := Minify([]byte("+]"))
+	c.Assert(errors.Is(err, syntax.ErrLoopDoesNotMatch), qt.IsTrue)
+}
@@ -0,0 +1,268 @@
+package bf
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/thesoulless/bf/internal/syntax"
+)
+
+// field numbers of the pprof profile.proto messages
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+	lineColumn     = 3
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// WritePprof writes the profile to w in the gzipped protocol buffer format of pprof, with
+// filename as the source file. Loops are shown as functions called from their enclosing
+// loop, or from main at the top level, so `go tool pprof` can list the hot loops and the
+// source lines in them.
+func (p *Profile) WritePprof(w io.Writer, filename string) error {
+	f := parseValid(p.src)
+	pb := newPprofBuilder(f)
+
+	var buf protoBuffer
+	valueType := func(typ, unit string) func(m *protoBuffer) {
+		return func(m *protoBuffer) {
+			m.int64(valueTypeType, pb.str(typ))
+			m.int64(valueTypeUnit, pb.str(unit))
+		}
+	}
+	buf.message(profileSampleType, valueType("steps", "count"))
+
+	for off, n := range p.Counts {
+		if n == 0 {
+			continue
+		}
+
+		// the brackets of a loop are in its parent, as the call of the loop
+		loops := f.Enclosing(off)
+		if n := len(loops); n > 0 && (loops[n-1].Open == off || loops[n-1].Close == off) {
+			loops = loops[:n-1]
+		}
+		locs := []uint64{pb.location(off, loops)}
+		for i := len(loops) - 1; i >= 0; i-- {
+			locs = append(locs, pb.location(loops[i].Open, loops[:i]))
+		}
+		buf.message(profileSample, func(m *protoBuffer) {
+			m.packed(sampleLocationID, locs)
+			m.packed(sampleValue, []uint64{uint64(n)})
+		})
+	}
+
+	for _, l := range pb.locs {
+		l := l
+		buf.message(profileLocation, func(m *protoBuffer) {
+			m.uint64(locationID, l.id)
+			m.message(locationLine, func(m *protoBuffer) {
+				m.uint64(lineFunctionID, l.function)
+				m.int64(lineLine, int64(l.line))
+				m.int64(lineColumn, int64(l.column))
+			})
+		})
+	}
+	for _, fn := range pb.funcs {
+		fn := fn
+		buf.message(profileFunction, func(m *protoBuffer) {
+			m.uint64(functionID, fn.id)
+			m.int64(functionName, fn.name)
+			m.int64(functionSystemName, fn.name)
+			m.int64(functionFilename, pb.str(filename))
+			m.int64(functionStartLine, int64(fn.line))
+		})
+	}
+
+	if !p.start.IsZero() {
+		buf.int64(profileTimeNanos, p.start.UnixNano())
+	}
+	buf.int64(profileDurationNanos, int64(p.Duration))
+	buf.message(profilePeriodType, valueType("steps", "count"))
+	buf.int64(profilePeriod, 1)
+
+	// the string table is written last, once all the strings are known
+	for _, s := range pb.strs {
+		buf.string(profileStringTable, s)
+	}
+
+	gz := gzip.NewWriter(w)
+	_, err := gz.Write(buf.data)
+	if err != nil {
+		return fmt.Errorf("failed writing the profile: %w", err)
+	}
+	err = gz.Close()
+	if err != nil {
+		return fmt.Errorf("failed writing the profile: %w", err)
+	}
+
+	return nil
+}
+
+// pprofBuilder assigns the IDs of the locations, the functions and the strings of a
+// pprof profile.
+type pprofBuilder struct {
+	file  *syntax.File
+	strs  []string
+	index map[string]int64
+
+	locs    []pprofLocation
+	locIDs  map[int]uint64 // by source offset
+	funcs   []pprofFunction
+	funcIDs map[int]uint64 // by offset of the loop, -1 for main
+}
+
+type pprofLocation struct {
+	id, function uint64
+	line, column int
+}
+
+type pprofFunction struct {
+	id   uint64
+	name int64
+	line int
+}
+
+func newPprofBuilder(f *syntax.File) *pprofBuilder {
+	return &pprofBuilder{
+		file:    f,
+		strs:    []string{""},
+		index:   map[string]int64{"": 0},
+		locIDs:  make(map[int]uint64),
+		funcIDs: make(map[int]uint64),
+	}
+}
+
+// str returns the index of s in the string table.
+func (b *pprofBuilder) str(s string) int64 {
+	i, ok := b.index[s]
+	if !ok {
+		i = int64(len(b.strs))
+		b.strs = append(b.strs, s)
+		b.index[s] = i
+	}
+	return i
+}
+
+// location returns the ID of the location of the command at offset, in the innermost of
+// loops.
+func (b *pprofBuilder) location(offset int, loops []*syntax.Loop) uint64 {
+	if id, ok := b.locIDs[offset]; ok {
+		return id
+	}
+
+	var fn uint64
+	if len(loops) == 0 {
+		fn = b.function(-1)
+	} else {
+		fn = b.function(loops[len(loops)-1].Open)
+	}
+
+	line, col := b.file.Position(offset)
+	id := uint64(len(b.locs) + 1)
+	b.locs = append(b.locs, pprofLocation{id: id, function: fn, line: line, column: col})
+	b.locIDs[offset] = id
+
+	return id
+}
+
+// function returns the ID of the function of the loop at offset, or of main for -1.
+func (b *pprofBuilder) function(offset int) uint64 {
+	if id, ok := b.funcIDs[offset]; ok {
+		return id
+	}
+
+	name, line := "main", 1
+	if offset >= 0 {
+		var col int
+		line, col = b.file.Position(offset)
+		name = fmt.Sprintf("loop %d:%d", line, col)
+	}
+
+	id := uint64(len(b.funcs) + 1)
+	b.funcs = append(b.funcs, pprofFunction{id: id, name: b.str(name), line: line})
+	b.funcIDs[offset] = id
+
+	return id
+}
+
+// protoBuffer encodes protocol buffer messages.
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) varint(x uint64) {
+	for x >= 0x80 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+// key writes the key of field with wire type typ.
+func (b *protoBuffer) key(field, typ int) {
+	b.varint(uint64(field)<<3 | uint64(typ))
+}
+
+// uint64 writes a varint field, leaving it out when zero like proto3 does.
+func (b *protoBuffer) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, 0)
+	b.varint(x)
+}
+
+func (b *protoBuffer) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+// packed writes a packed repeated varint field.
+func (b *protoBuffer) packed(field int, xs []uint64) {
+	var p protoBuffer
+	for _, x := range xs {
+		p.varint(x)
+	}
+	b.bytes(field, p.data)
+}
+
+func (b *protoBuffer) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+func (b *protoBuffer) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+func (b *protoBuffer) message(field int, fn func(m *protoBuffer)) {
+	var m protoBuffer
+	fn(&m)
+	b.bytes(field, m.data)
+}
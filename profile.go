@@ -0,0 +1,186 @@
+package bf
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// clockEvery is the number of commands between two readings of the clock, outside of
+// loop entries and exits.
+const clockEvery = 4096
+
+// LoopStats is the profile of a loop.
+type LoopStats struct {
+	Pos        Position      // position of the opening bracket
+	Entries    int64         // times the loop was entered, rather than skipped
+	Iterations int64         // iterations over all the entries
+	Steps      int64         // commands run in the loop, including inner loops and its brackets
+	Time       time.Duration // time spent in the loop
+	// Histogram counts the entries by their number of iterations: Histogram[i] counts
+	// the entries with 2^i to 2^(i+1)-1 iterations.
+	Histogram []int64
+}
+
+// Profile collects how many times each command runs, and where loops spend their time.
+// Register it with WithProfile. It is not safe to read while the program runs.
+type Profile struct {
+	Steps    int64              // commands run
+	Duration time.Duration      // approximate time between the first and the last command
+	Counts   []int64            // runs of each command, indexed by source offset
+	Loops    map[int]*LoopStats // loops entered at least once, keyed by the offset of '['
+
+	NopHooks
+	src    []byte
+	start  time.Time
+	active []activeLoop
+}
+
+// activeLoop is a loop being run.
+type activeLoop struct {
+	stats *LoopStats
+	iters int64
+	steps int64 // Steps when the loop was entered
+	start time.Time
+}
+
+// WithProfile profiles the execution into p, which is reset.
+func WithProfile(p *Profile) Option {
+	return func(b *BF) {
+		*p = Profile{
+			Counts: make([]int64, len(b.src)),
+			Loops:  make(map[int]*LoopStats),
+			src:    b.src,
+		}
+		WithHooks(p)(b)
+	}
+}
+
+// BeforeInstruction implements Hooks.
+func (p *Profile) BeforeInstruction(pos Position, cmd rune) {
+	if p.Steps == 0 {
+		p.start = time.Now()
+	}
+	p.Steps++
+	p.Counts[pos.Offset]++
+
+	if cmd == ']' && len(p.active) > 0 {
+		// each iteration runs the closing bracket once
+		p.active[len(p.active)-1].iters++
+	}
+	if p.Steps%clockEvery == 0 {
+		p.Duration = time.Since(p.start)
+	}
+}
+
+// OnLoopEnter implements Hooks.
+func (p *Profile) OnLoopEnter(pos Position) {
+	s, ok := p.Loops[pos.Offset]
+	if !ok {
+		s = &LoopStats{Pos: pos}
+		p.Loops[pos.Offset] = s
+	}
+	s.Entries++
+	p.active = append(p.active, activeLoop{stats: s, steps: p.Steps, start: time.Now()})
+}
+
+// OnLoopExit implements Hooks.
+func (p *Profile) OnLoopExit(Position) {
+	if len(p.active) == 0 {
+		return
+	}
+	l := p.active[len(p.active)-1]
+	p.active = p.active[:len(p.active)-1]
+
+	now := time.Now()
+	s := l.stats
+	s.Iterations += l.iters
+	s.Steps += p.Steps - l.steps + 1 // and the opening bracket
+	s.Time += now.Sub(l.start)
+
+	if i := bits.Len64(uint64(l.iters)) - 1; i >= 0 {
+		for len(s.Histogram) <= i {
+			s.Histogram = append(s.Histogram, 0)
+		}
+		s.Histogram[i]++
+	}
+
+	p.Duration = now.Sub(p.start)
+}
+
+// OnOutput implements Hooks.
+func (p *Profile) OnOutput(int32) {
+	p.Duration = time.Since(p.start)
+}
+
+// OnError implements Hooks.
+func (p *Profile) OnError(Position, error) {
+	if p.Steps > 0 {
+		p.Duration = time.Since(p.start)
+	}
+}
+
+// HotLoops returns the loops sorted by the number of commands run in them, the
+// hottest first.
+func (p *Profile) HotLoops() []*LoopStats {
+	loops := make([]*LoopStats, 0, len(p.Loops))
+	for _, l := range p.Loops {
+		loops = append(loops, l)
+	}
+	sort.Slice(loops, func(i, j int) bool {
+		if loops[i].Steps != loops[j].Steps {
+			return loops[i].Steps > loops[j].Steps
+		}
+		return loops[i].Pos.Offset < loops[j].Pos.Offset
+	})
+
+	return loops
+}
+
+// Report writes a report of the n hottest loops to w, or of all of them when n is zero.
+func (p *Profile) Report(w io.Writer, n int) error {
+	loops := p.HotLoops()
+	if n > 0 && len(loops) > n {
+		loops = loops[:n]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d steps in %v\n", p.Steps, p.Duration)
+	if len(loops) == 0 {
+		return tw.Flush()
+	}
+
+	fmt.Fprintf(tw, "\nloop\tsteps\tshare\tentries\titerations\ttime\titerations per entry\n")
+	for _, l := range loops {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%d\t%d\t%v\t%s\n",
+			l.Pos, l.Steps, 100*float64(l.Steps)/float64(p.Steps), l.Entries, l.Iterations,
+			l.Time.Round(time.Microsecond), histogram(l.Histogram))
+	}
+
+	return tw.Flush()
+}
+
+// histogram formats h as "range:count" pairs, e.g. "1:3 4-7:2".
+func histogram(h []int64) string {
+	s := ""
+	for i, n := range h {
+		if n == 0 {
+			continue
+		}
+		if s != "" {
+			s += " "
+		}
+
+		lo, hi := int64(1)<<i, int64(1)<<(i+1)-1
+		if lo == hi {
+			s += fmt.Sprintf("%d:%d", lo, n)
+		} else {
+			s += fmt.Sprintf("%d-%d:%d", lo, hi, n)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,96 @@
+package bf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWithProfile(t *testing.T) {
+	c := qt.New(t)
+
+	src := "+++[>++[>+<-]<-]>[-]"
+	var p Profile
+	bfi, err := New(strings.NewReader(src), io.Discard, nil, WithProfile(&p))
+	c.Assert(err, qt.IsNil)
+	err = bfi.Exec()
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(p.Steps, qt.Equals, int64(57))
+	c.Assert(p.Counts[0], qt.Equals, int64(1))
+	c.Assert(p.Counts[4], qt.Equals, int64(3))  // '>' of the outer loop
+	c.Assert(p.Counts[10], qt.Equals, int64(6)) // '<' of the inner loop
+	c.Assert(p.Counts[17], qt.Equals, int64(1)) // the skipped loop
+	c.Assert(p.Counts[18], qt.Equals, int64(0))
+
+	c.Assert(p.Loops, qt.HasLen, 2)
+	outer, inner := p.Loops[3], p.Loops[7]
+	c.Assert(outer.Pos, qt.Equals, Position{Offset: 3, Line: 1, Column: 4})
+	c.Assert(outer.Entries, qt.Equals, int64(1))
+	c.Assert(outer.Iterations, qt.Equals, int64(3))
+	c.Assert(outer.Steps, qt.Equals, int64(52))
+	c.Assert(outer.Histogram, qt.DeepEquals, []int64{0, 1})
+
+	c.Assert(inner.Entries, qt.Equals, int64(3))
+	c.Assert(inner.Iterations, qt.Equals, int64(6))
+	c.Assert(inner.Steps, qt.Equals, int64(33))
+	c.Assert(inner.Histogram, qt.DeepEquals, []int64{0, 3})
+
+	c.Assert(p.HotLoops(), qt.DeepEquals, []*LoopStats{outer, inner})
+
+	t.Run("report", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := p.Report(&buf, 1)
+		c.Assert(err, qt.IsNil)
+
+		lines := strings.Split(buf.String(), "\n")
+		c.Assert(lines, qt.HasLen, 5)
+		c.Assert(strings.HasPrefix(lines[0], "57 steps in "), qt.IsTrue)
+		c.Assert(strings.Fields(lines[2])[:5], qt.DeepEquals, []string{"loop", "steps", "share", "entries", "iterations"})
+		fields := strings.Fields(lines[3])
+		c.Assert(fields[:5], qt.DeepEquals, []string{"1:4", "52", "91.2%", "1", "3"})
+		c.Assert(fields[len(fields)-1], qt.Equals, "2-3:1")
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := p.WritePprof(&buf, "prog.bf")
+		c.Assert(err, qt.IsNil)
+
+		r, err := gzip.NewReader(&buf)
+		c.Assert(err, qt.IsNil)
+		data, err := io.ReadAll(r)
+		c.Assert(err, qt.IsNil)
+		for _, s := range []string{"steps", "count", "main", "loop 1:4", "loop 1:8", "prog.bf"} {
+			c.Assert(bytes.Contains(data, []byte(s)), qt.IsTrue, qt.Commentf(s))
+		}
+	})
+}
+
+func TestHistogram(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(histogram(nil), qt.Equals, "")
+	c.Assert(histogram([]int64{3, 0, 2, 1}), qt.Equals, "1:3 4-7:2 8-15:1")
+}
+
+func TestProtoBuffer(t *testing.T) {
+	c := qt.New(t)
+
+	var b protoBuffer
+	b.uint64(1, 150)
+	b.uint64(2, 0) // left out
+	b.string(3, "ab")
+	b.packed(4, []uint64{1, 300})
+	b.message(5, func(m *protoBuffer) { m.int64(1, 1) })
+	c.Assert(b.data, qt.DeepEquals, []byte{
+		0x08, 0x96, 0x01,
+		0x1a, 0x02, 'a', 'b',
+		0x22, 0x03, 0x01, 0xac, 0x02,
+		0x2a, 0x02, 0x08, 0x01,
+	})
+}
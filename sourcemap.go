@@ -0,0 +1,39 @@
+package bf
+
+import "github.com/thesoulless/bf/sourcemap"
+
+// WithSourceMap maps the commands back to the original sources they were made from with
+// m, e.g. for a minified or a generated program. The positions of the commands, as given
+// to the hooks, returned by Pos, Position and the breakpoints, written in the traces, and
+// in the errors of a run, get their Orig. Errors returned by New keep the positions in
+// the program, as they are found before the options apply.
+func WithSourceMap(m *sourcemap.Map) Option {
+	return func(b *BF) {
+		b.smap = m
+	}
+}
+
+// orig returns the original position of the command at p, or nil if the source map does
+// not cover it.
+func (b *BF) orig(p Position) *SourcePosition {
+	m, ok := b.smap.Lookup(p.Line, p.Column)
+	if !ok {
+		return nil
+	}
+	return &SourcePosition{File: b.smap.Sources[m.Source], Line: m.SourceLine, Column: m.SourceColumn}
+}
+
+// SourceOffset returns the offset of the first command made from line:column of the
+// original source file, or from anywhere on the line if column is zero, using the source
+// map of WithSourceMap. It returns ErrInvalidPosition without a source map, or when no
+// command was made from the position.
+func (b *BF) SourceOffset(file string, line, column int) (int, error) {
+	if b.smap == nil {
+		return 0, ErrInvalidPosition
+	}
+	m, ok := b.smap.Generated(file, line, column)
+	if !ok {
+		return 0, ErrInvalidPosition
+	}
+	return b.Offset(m.Line, m.Column)
+}
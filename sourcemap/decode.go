@@ -0,0 +1,176 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrInvalid is returned for a source map that can not be decoded.
+var ErrInvalid = errors.New("invalid source map")
+
+// Read reads a source map, and decodes its mappings.
+func Read(r io.Reader) (*Map, error) {
+	var m Map
+	err := json.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("%w: version %d, want %d", ErrInvalid, m.Version, Version)
+	}
+
+	_, err = m.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ReadFile reads the source map in the file path, like Read, and joins the relative paths
+// of its sources to the directory of path, so they can be opened from the working
+// directory.
+func ReadFile(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, src := range m.Sources {
+		if !filepath.IsAbs(src) {
+			m.Sources[i] = filepath.Join(filepath.Dir(path), src)
+		}
+	}
+	return m, nil
+}
+
+// Decode returns the mappings of m, ordered by their position in the program.
+func (m *Map) Decode() ([]Mapping, error) {
+	if m.decoded != nil {
+		return m.decoded, nil
+	}
+
+	mappings := []Mapping{}
+	var col, src, srcLine, srcCol int
+	for i, group := range strings.Split(m.Mappings, ";") {
+		col = 0
+		if group == "" {
+			continue
+		}
+		for _, seg := range strings.Split(group, ",") {
+			fields, err := readVLQs(seg)
+			if err != nil {
+				return nil, err
+			}
+			switch len(fields) {
+			case 1:
+				// a position of the program without a source
+				col += fields[0]
+				continue
+			case 4, 5:
+			default:
+				return nil, fmt.Errorf("%w: segment %q has %d fields", ErrInvalid, seg, len(fields))
+			}
+
+			col += fields[0]
+			src += fields[1]
+			srcLine += fields[2]
+			srcCol += fields[3]
+			if col < 0 || src < 0 || src >= len(m.Sources) || srcLine < 0 || srcCol < 0 {
+				return nil, fmt.Errorf("%w: segment %q is out of range", ErrInvalid, seg)
+			}
+			mappings = append(mappings, Mapping{
+				Line:         i + 1,
+				Column:       col + 1,
+				Source:       src,
+				SourceLine:   srcLine + 1,
+				SourceColumn: srcCol + 1,
+			})
+		}
+	}
+	sort.SliceStable(mappings, func(i, j int) bool {
+		a, b := mappings[i], mappings[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+
+	m.decoded = mappings
+	return mappings, nil
+}
+
+// Lookup returns the mapping of the position line:column of the program, which is the
+// last one at or before it on its line. It returns false if there is none, or if the
+// mappings can not be decoded.
+func (m *Map) Lookup(line, column int) (Mapping, bool) {
+	mappings, err := m.Decode()
+	if err != nil {
+		return Mapping{}, false
+	}
+
+	i := sort.Search(len(mappings), func(i int) bool {
+		mp := mappings[i]
+		return mp.Line > line || (mp.Line == line && mp.Column > column)
+	})
+	if i == 0 || mappings[i-1].Line != line {
+		return Mapping{}, false
+	}
+	return mappings[i-1], true
+}
+
+// Generated returns the first mapping, in the order of the program, to line of the source
+// named source, at the column if it is not zero. It returns false if there is none, or if
+// the mappings can not be decoded.
+func (m *Map) Generated(source string, line, column int) (Mapping, bool) {
+	mappings, err := m.Decode()
+	if err != nil {
+		return Mapping{}, false
+	}
+
+	for _, mp := range mappings {
+		if m.Sources[mp.Source] == source && mp.SourceLine == line &&
+			(column == 0 || mp.SourceColumn == column) {
+			return mp, true
+		}
+	}
+	return Mapping{}, false
+}
+
+// readVLQs reads the base64 VLQ fields of a segment.
+func readVLQs(seg string) ([]int, error) {
+	var fields []int
+	v, shift := 0, 0
+	for i := 0; i < len(seg); i++ {
+		digit := strings.IndexByte(base64, seg[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("%w: invalid character %q in segment %q", ErrInvalid, seg[i], seg)
+		}
+		if shift > 30 {
+			return nil, fmt.Errorf("%w: segment %q overflows", ErrInvalid, seg)
+		}
+		v |= (digit & 31) << shift
+		shift += 5
+		if digit&32 != 0 {
+			continue
+		}
+
+		if v&1 != 0 {
+			fields = append(fields, -(v >> 1))
+		} else {
+			fields = append(fields, v>>1)
+		}
+		v, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("%w: segment %q ends in a field", ErrInvalid, seg)
+	}
+	return fields, nil
+}
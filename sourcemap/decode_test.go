@@ -0,0 +1,92 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRead(t *testing.T) {
+	c := qt.New(t)
+
+	mappings := []Mapping{
+		{Line: 1, Column: 1, Source: 0, SourceLine: 1, SourceColumn: 1},
+		{Line: 1, Column: 2, Source: 0, SourceLine: 1, SourceColumn: 5},
+		{Line: 1, Column: 40, Source: 0, SourceLine: 3, SourceColumn: 2},
+		{Line: 3, Column: 1, Source: 1, SourceLine: 100, SourceColumn: 1},
+	}
+	data, err := json.Marshal(New("out.bf", []string{"a.bf", "b.bf"}, mappings))
+	c.Assert(err, qt.IsNil)
+
+	m, err := Read(strings.NewReader(string(data)))
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.File, qt.Equals, "out.bf")
+	got, err := m.Decode()
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, mappings)
+
+	mp, ok := m.Lookup(1, 30)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(mp, qt.Equals, mappings[1])
+	mp, ok = m.Lookup(3, 1)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(mp, qt.Equals, mappings[3])
+	_, ok = m.Lookup(2, 1)
+	c.Assert(ok, qt.IsFalse)
+
+	mp, ok = m.Generated("a.bf", 3, 0)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(mp, qt.Equals, mappings[2])
+	_, ok = m.Generated("b.bf", 100, 2)
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := []struct {
+		name, data, err string
+	}{
+		{"json", `{`, "invalid source map: unexpected EOF"},
+		{"version", `{"version":2,"sources":[],"mappings":""}`, "invalid source map: version 2, want 3"},
+		{"character", `{"version":3,"sources":["a"],"mappings":"AA!A"}`, `invalid source map: invalid character '!' in segment "AA!A"`},
+		{"fields", `{"version":3,"sources":["a"],"mappings":"AA"}`, `invalid source map: segment "AA" has 2 fields`},
+		{"source", `{"version":3,"sources":["a"],"mappings":"ACAA"}`, `invalid source map: segment "ACAA" is out of range`},
+		{"continued", `{"version":3,"sources":["a"],"mappings":"AAAg"}`, `invalid source map: segment "AAAg" ends in a field`},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			_, err := Read(strings.NewReader(test.data))
+			c.Assert(errors.Is(err, ErrInvalid), qt.IsTrue)
+			c.Assert(err, qt.ErrorMatches, regexp.QuoteMeta(test.err))
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.bf.map")
+	abs := filepath.Join(dir, "abs.bf")
+	data, err := json.Marshal(New("out.bf", []string{"src/a.bf", abs}, nil))
+	c.Assert(err, qt.IsNil)
+	err = os.WriteFile(path, data, 0o600)
+	c.Assert(err, qt.IsNil)
+
+	m, err := ReadFile(path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(m.Sources, qt.DeepEquals, []string{filepath.Join(dir, "src", "a.bf"), abs})
+
+	err = os.WriteFile(path, []byte("{}"), 0o600)
+	c.Assert(err, qt.IsNil)
+	_, err = ReadFile(path)
+	c.Assert(err, qt.ErrorMatches, ".*out.bf.map: invalid source map: version 0, want 3")
+}
@@ -0,0 +1,92 @@
+// Package sourcemap links the commands of a transformed BF program, like a minified or a
+// generated one, back to the sources it was made from. Source maps are JSON, in the
+// format of version 3 of the JavaScript source maps, so the tools made for them can read
+// them, with columns counted in bytes. The paths of the sources are relative to the
+// directory of the source map.
+package sourcemap
+
+import (
+	"strings"
+)
+
+// Version is the version of the source map format.
+const Version = 3
+
+// Map is a source map.
+type Map struct {
+	Version int    `json:"version"`
+	File    string `json:"file,omitempty"` // the generated program
+	// Sources are the files the program was made from.
+	Sources []string `json:"sources"`
+	Names   []string `json:"names"` // not used, always empty
+	// Mappings are the encoded mappings, a group of segments for each line of the
+	// program. Each segment is a list of base64 VLQ fields: the column in the program,
+	// the index of the source, and the line and the column in the source, all starting
+	// at 0, and relative to the previous segment.
+	Mappings string `json:"mappings"`
+
+	decoded []Mapping // nil until decoded
+}
+
+// Mapping links a position of the generated program to a position in one of the sources.
+// Lines and columns start at 1, and columns count bytes.
+type Mapping struct {
+	Line, Column             int // in the generated program
+	Source                   int // index in Map.Sources
+	SourceLine, SourceColumn int
+}
+
+// New returns the source map of the generated program file, made from sources. The
+// mappings are ordered by their position in the program.
+func New(file string, sources []string, mappings []Mapping) *Map {
+	var b strings.Builder
+	line := 1
+	var col, src, srcLine, srcCol int // previous values, starting at 0
+	for i, m := range mappings {
+		if m.Line != line {
+			for ; line < m.Line; line++ {
+				b.WriteByte(';')
+			}
+			col = 0
+		} else if i > 0 {
+			b.WriteByte(',')
+		}
+
+		writeVLQ(&b, m.Column-1-col)
+		writeVLQ(&b, m.Source-src)
+		writeVLQ(&b, m.SourceLine-1-srcLine)
+		writeVLQ(&b, m.SourceColumn-1-srcCol)
+		col, src, srcLine, srcCol = m.Column-1, m.Source, m.SourceLine-1, m.SourceColumn-1
+	}
+
+	return &Map{
+		Version:  Version,
+		File:     file,
+		Sources:  sources,
+		Names:    []string{},
+		Mappings: b.String(),
+		decoded:  append([]Mapping(nil), mappings...),
+	}
+}
+
+const base64 = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes v as a base64 VLQ: the sign in the lowest bit, then groups of 5 bits,
+// the lowest first, with a continuation bit.
+func writeVLQ(b *strings.Builder, v int) {
+	u := uint(v) << 1
+	if v < 0 {
+		u = uint(-v)<<1 | 1
+	}
+	for {
+		digit := u & 31
+		u >>= 5
+		if u > 0 {
+			digit |= 32
+		}
+		b.WriteByte(base64[digit])
+		if u == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWriteVLQ(t *testing.T) {
+	c := qt.New(t)
+
+	for v, want := range map[int]string{
+		0: "A", 1: "C", -1: "D", 15: "e", -15: "f", 16: "gB", 123: "2H", -1000: "x+B",
+	} {
+		var b strings.Builder
+		writeVLQ(&b, v)
+		c.Assert(b.String(), qt.Equals, want, qt.Commentf("%d", v))
+	}
+}
+
+func TestNew(t *testing.T) {
+	c := qt.New(t)
+
+	m := New("out.bf", []string{"a.bf", "b.bf"}, []Mapping{
+		{Line: 1, Column: 1, Source: 0, SourceLine: 1, SourceColumn: 1},
+		{Line: 1, Column: 2, Source: 0, SourceLine: 1, SourceColumn: 5},
+		{Line: 1, Column: 3, Source: 0, SourceLine: 3, SourceColumn: 2},
+		{Line: 3, Column: 1, Source: 1, SourceLine: 1, SourceColumn: 1},
+	})
+
+	data, err := json.Marshal(m)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals,
+		`{"version":3,"file":"out.bf","sources":["a.bf","b.bf"],"names":[],"mappings":"AAAA,CAAI,CAEH;;ACFD"}`)
+}
@@ -0,0 +1,73 @@
+package bf
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/thesoulless/bf/sourcemap"
+)
+
+// testMap maps the program "+[<]." to the commands of the source "+\n[ <\n]\n.", which
+// start on lines 1 to 4 of orig.bf.
+func testMap() *sourcemap.Map {
+	return sourcemap.New("min.bf", []string{"orig.bf"}, []sourcemap.Mapping{
+		{Line: 1, Column: 1, SourceLine: 1, SourceColumn: 1},
+		{Line: 1, Column: 2, SourceLine: 2, SourceColumn: 1},
+		{Line: 1, Column: 3, SourceLine: 2, SourceColumn: 3},
+		{Line: 1, Column: 4, SourceLine: 3, SourceColumn: 1},
+		{Line: 1, Column: 5, SourceLine: 4, SourceColumn: 1},
+	})
+}
+
+func TestWithSourceMap(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("errors", func(t *testing.T) {
+		var trace bytes.Buffer
+		bfi, err := New(strings.NewReader("+[<]."), io.Discard, nil,
+			WithSourceMap(testMap()), WithTrace(&trace, TraceFilter{}))
+		c.Assert(err, qt.IsNil)
+
+		_, err = bfi.Continue()
+		c.Assert(err, qt.ErrorIs, ErrNegativeIndex)
+		c.Assert(err, qt.ErrorMatches, `.* at orig.bf:2:3 \(1:3 in the program\)`)
+
+		events := readTrace(c, &trace)
+		c.Assert(events, qt.HasLen, 2)
+		c.Assert(events[1].Orig, qt.DeepEquals, &SourcePosition{File: "orig.bf", Line: 2, Column: 1})
+		c.Assert(events[1].String(), qt.Equals, "step 2 at 1:2 (orig.bf:2:1): [ ptr=0 cell=1")
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		bfi, err := New(strings.NewReader("+[<].\n>"), io.Discard, nil, WithSourceMap(testMap()))
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(bfi.Pos().Orig, qt.DeepEquals, &SourcePosition{File: "orig.bf", Line: 1, Column: 1})
+		c.Assert(bfi.Position(4).Orig.String(), qt.Equals, "orig.bf:4:1")
+		// not covered by the map
+		c.Assert(bfi.Position(6).Orig, qt.IsNil)
+
+		off, err := bfi.SourceOffset("orig.bf", 3, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(off, qt.Equals, 3)
+		off, err = bfi.SourceOffset("orig.bf", 2, 3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(off, qt.Equals, 2)
+		_, err = bfi.SourceOffset("orig.bf", 2, 2)
+		c.Assert(err, qt.ErrorIs, ErrInvalidPosition)
+		_, err = bfi.SourceOffset("other.bf", 1, 0)
+		c.Assert(err, qt.ErrorIs, ErrInvalidPosition)
+	})
+
+	t.Run("no map", func(t *testing.T) {
+		bfi, err := New(strings.NewReader("+[<]"), io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+
+		c.Assert(bfi.Pos().Orig, qt.IsNil)
+		_, err = bfi.SourceOffset("orig.bf", 1, 1)
+		c.Assert(err, qt.ErrorIs, ErrInvalidPosition)
+	})
+}
@@ -0,0 +1,80 @@
+package bf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Stats is a report of the execution of a BF.
+type Stats struct {
+	Steps        int64          // commands run
+	Ops          map[rune]int64 // runs of each command
+	MaxPointer   int            // highest data pointer reached
+	Cells        int            // tape cells allocated
+	InputBytes   int64          // input consumed, including the line ends
+	OutputBytes  int64          // output written
+	MaxLoopDepth int            // deepest loop nesting reached
+	// Wall is the time spent in Exec, Continue, RunUntil, StepOver and StepOut. Time
+	// spent in Step alone is not measured.
+	Wall time.Duration
+}
+
+// Stats returns the statistics of the execution so far. Commands reverted by StepBack
+// are not counted in Steps and Ops.
+func (b *BF) Stats() Stats {
+	s := Stats{
+		Steps:        b.steps,
+		Ops:          make(map[rune]int64),
+		MaxPointer:   b.maxPtr,
+		Cells:        len(b.arr),
+		InputBytes:   b.inBytes,
+		OutputBytes:  b.outBytes,
+		MaxLoopDepth: b.maxDepth,
+		Wall:         b.wall,
+	}
+	for op, n := range b.ops {
+		if n > 0 {
+			s.Ops[rune(op)] = n
+		}
+	}
+
+	return s
+}
+
+// Report writes the statistics to w, with the runs of the default commands first,
+// followed by the others.
+func (s Stats) Report(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "steps\t%d\n", s.Steps)
+	for _, op := range s.opOrder() {
+		fmt.Fprintf(tw, "  %c\t%d\n", op, s.Ops[op])
+	}
+	fmt.Fprintf(tw, "max pointer\t%d\n", s.MaxPointer)
+	fmt.Fprintf(tw, "cells\t%d\n", s.Cells)
+	fmt.Fprintf(tw, "input bytes\t%d\n", s.InputBytes)
+	fmt.Fprintf(tw, "output bytes\t%d\n", s.OutputBytes)
+	fmt.Fprintf(tw, "max loop depth\t%d\n", s.MaxLoopDepth)
+	fmt.Fprintf(tw, "wall time\t%v\n", s.Wall)
+	return tw.Flush()
+}
+
+// opOrder returns the commands that ran, the default ones first.
+func (s Stats) opOrder() []rune {
+	var ops, other []rune
+	for _, op := range []rune{'>', '<', '+', '-', '.', ',', '[', ']'} {
+		if s.Ops[op] > 0 {
+			ops = append(ops, op)
+		}
+	}
+	for op := range s.Ops {
+		if !strings.ContainsRune("><+-.,[]", op) {
+			other = append(other, op)
+		}
+	}
+	sort.Slice(other, func(i, j int) bool { return other[i] < other[j] })
+	return append(ops, other...)
+}
@@ -0,0 +1,51 @@
+package bf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestStats(t *testing.T) {
+	c := qt.New(t)
+
+	src := ",[>++[>+<-]<-]>>>>."
+	var out bytes.Buffer
+	bfi, err := New(strings.NewReader(src), &out, strings.NewReader("2\n"), WithHistory(0))
+	c.Assert(err, qt.IsNil)
+	err = bfi.Exec()
+	c.Assert(err, qt.IsNil)
+
+	s := bfi.Stats()
+	c.Assert(s.Steps, qt.Equals, int64(41))
+	c.Assert(s.Ops, qt.DeepEquals, map[rune]int64{
+		',': 1, '[': 3, ']': 6, '>': 10, '<': 6, '+': 8, '-': 6, '.': 1,
+	})
+	c.Assert(s.MaxPointer, qt.Equals, 4)
+	c.Assert(s.Cells, qt.Equals, 5) // allocated up front
+	c.Assert(s.InputBytes, qt.Equals, int64(2))
+	c.Assert(s.OutputBytes, qt.Equals, int64(1))
+	c.Assert(s.MaxLoopDepth, qt.Equals, 2)
+	c.Assert(s.Wall > 0, qt.IsTrue)
+
+	t.Run("step back", func(t *testing.T) {
+		_, err := bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		s := bfi.Stats()
+		c.Assert(s.Steps, qt.Equals, int64(40))
+		c.Assert(s.Ops['.'], qt.Equals, int64(0))
+	})
+
+	t.Run("report", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := bfi.Stats().Report(&buf)
+		c.Assert(err, qt.IsNil)
+
+		lines := strings.Split(buf.String(), "\n")
+		c.Assert(lines[0], qt.Equals, "steps           40")
+		c.Assert(lines[1], qt.Equals, "  >             10")
+		c.Assert(strings.HasPrefix(lines[len(lines)-2], "wall time"), qt.IsTrue)
+	})
+}
@@ -0,0 +1,427 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrInvalidPosition is returned when a position does not point to a command in the source.
+	ErrInvalidPosition = errors.New("position does not point to a command")
+	// ErrInterrupted is returned when a run is stopped by Interrupt.
+	ErrInterrupted = errors.New("interrupted")
+)
+
+// Position describes a location in the source of commands.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number, starting at 1 (byte count)
+	// Orig is the position in the original source the command was made from, given by
+	// the source map of WithSourceMap. It is nil without a source map, or when the map
+	// does not cover the command.
+	Orig *SourcePosition
+}
+
+// String returns the position in the line:column form.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SourcePosition is a position in an original source.
+type SourcePosition struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"` // starting at 1
+	Column int    `json:"col"`  // starting at 1 (byte count)
+}
+
+// String returns the position in the file:line:column form.
+func (p SourcePosition) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// PositionError is an error of a run, with the position it happened at: the command that
+// failed, or the loop that never ends. Step, Continue, RunUntil, StepOver and StepOut
+// return the errors of the commands as a *PositionError, which Unwrap gives back.
+type PositionError struct {
+	Pos Position
+	Err error
+}
+
+// Error returns the error with its position, and with its original position when a
+// source map gives one.
+func (e *PositionError) Error() string {
+	if e.Pos.Orig != nil {
+		return fmt.Sprintf("%v at %s (%s in the program)", e.Err, e.Pos.Orig, e.Pos)
+	}
+	return fmt.Sprintf("%v at %s (line:column)", e.Err, e.Pos)
+}
+
+// Unwrap returns the error without its position.
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// Step executes the next command and returns its position. Whitespace and
+// comments are skipped, so each call runs exactly one command. It returns
+// io.EOF once there are no commands left to run. The errors of the command
+// are returned as a *PositionError, with its position. A failed command is not
+// run again: the program stays on it, and every later call returns the same error.
+func (b *BF) Step() (Position, error) {
+	at, err := b.next()
+	return b.position(at), err
+}
+
+// next works like Step, returning the offset of the command. The position of the
+// command is only found when a hook, the trace or a watchpoint needs it, or the
+// command fails.
+func (b *BF) next() (int, error) {
+	if b.err != nil {
+		return b.pc, b.err
+	}
+
+	err := b.skipComments()
+	if err != nil {
+		if b.hooks != nil {
+			b.hooks.OnError(b.position(b.pc), err)
+		}
+		return b.pc, b.fail(b.pc, err)
+	}
+	if b.pc >= len(b.src) {
+		return b.pc, io.EOF
+	}
+
+	at := b.pc
+	ch := rune(b.src[at])
+	var p Position
+	if b.hooks != nil || b.trace != nil || len(b.watches) > 0 {
+		p = b.position(at)
+	}
+	if b.hooks != nil {
+		b.hooks.BeforeInstruction(p, ch)
+	}
+
+	b.hits = nil
+	ptr, old := b.c, b.arr[b.c]
+	if b.history {
+		b.record()
+	}
+
+	err = b.step()
+	if b.redo > 0 {
+		b.redo--
+	}
+	if err != nil {
+		if b.history {
+			// the command did not run, so there is nothing to revert
+			b.log = b.log[:len(b.log)-1]
+		}
+		p = b.position(at)
+		if b.hooks != nil {
+			b.hooks.OnError(p, err)
+		}
+		return at, b.fail(at, nextErr(err, p))
+	}
+
+	b.steps++
+	b.ops[ch]++
+	if b.trace != nil {
+		err = b.trace.record(b, p, ch)
+		if err != nil {
+			if b.hooks != nil {
+				b.hooks.OnError(p, err)
+			}
+			return at, b.fail(at, err)
+		}
+	}
+
+	if b.cycles != nil {
+		err = b.cycles.update(b, at, ch, ptr, old)
+		if err != nil {
+			open := at
+			if ch == ']' {
+				open = b.jump[open]
+			}
+			err = nextErr(err, b.position(open))
+			if b.hooks != nil {
+				b.hooks.OnError(b.position(at), err)
+			}
+			return at, b.fail(at, err)
+		}
+	}
+
+	if len(b.watches) > 0 {
+		b.watch(p, ch, ptr, b.c, old, b.Cell(ptr))
+	}
+	return at, nil
+}
+
+// observed reports whether running a command takes more than step: hooks, a trace, loop
+// detection, watchpoints or the history.
+func (b *BF) observed() bool {
+	return b.hooks != nil || b.trace != nil || b.cycles != nil || len(b.watches) > 0 || b.history
+}
+
+// run executes commands like next, for a BF that is not observed, without finding the
+// positions of the commands unless one fails. It runs until the program ends or fails,
+// or with brk, until the next command is at stop or on a breakpoint, or Interrupt is
+// called, always running the first command. It returns the offset of the next command
+// to run, or of the command that failed.
+func (b *BF) run(stop int, brk bool) (int, error) {
+	if b.err != nil {
+		return b.pc, b.err
+	}
+
+	var cmds [256]bool
+	for ch := range cmds {
+		cmds[ch] = b.isCommand(rune(ch))
+	}
+
+	for first := true; ; first = false {
+		for ; b.pc < len(b.src); b.pc++ {
+			if ch := b.src[b.pc]; ch == 0 || cmds[ch] {
+				break
+			}
+		}
+		at := b.pc
+		if at >= len(b.src) {
+			return at, io.EOF
+		}
+		if brk && !first {
+			if _, ok := b.bps[at]; ok || at == stop {
+				return at, nil
+			}
+			if atomic.LoadInt32(&b.intr) == 1 && b.interrupted() {
+				return at, ErrInterrupted
+			}
+		}
+
+		ch := b.src[at]
+		if ch == 0 {
+			return at, b.fail(at, nextErr(ErrIllegalCharNul, b.position(at)))
+		}
+		err := b.step()
+		if err != nil {
+			return at, b.fail(at, nextErr(err, b.position(at)))
+		}
+		b.steps++
+		b.ops[ch]++
+	}
+}
+
+// fail stops the program on the command at off, which failed with err. The scanner
+// is moved back on the command, so Pos reports it, and err is returned by every later
+// run, until StepBack reverts a command.
+func (b *BF) fail(off int, err error) error {
+	b.pc = off
+	b.err = err
+	return err
+}
+
+// StepOver works like Step, but runs a whole loop when the next command opens one,
+// unless a breakpoint is reached or a watchpoint triggers inside it. Unlike Step, it
+// returns the position of the next command to run.
+func (b *BF) StepOver() (Position, error) {
+	next := b.peek(b.pc)
+	if next >= len(b.src) || b.src[next] != '[' {
+		_, err := b.next()
+		return b.Pos(), err
+	}
+
+	return b.runUntil(b.peek(b.jump[next] + 1))
+}
+
+// StepOut executes commands until the innermost loop being run ends, unless a breakpoint
+// is reached or a watchpoint triggers first. Outside of loops, it works like Continue.
+// It returns the position of the next command to run.
+func (b *BF) StepOut() (Position, error) {
+	if len(b.lpos) == 0 {
+		return b.runUntil(-1)
+	}
+
+	return b.runUntil(b.peek(b.jump[b.lpos[len(b.lpos)-1]] + 1))
+}
+
+// Interrupt stops a running Continue, RunUntil, StepOver, StepOut or ReverseContinue after
+// the command being run, making it return ErrInterrupted. It is safe to call from another
+// goroutine. If nothing is running, the next run stops after its first command.
+func (b *BF) Interrupt() {
+	atomic.StoreInt32(&b.intr, 1)
+}
+
+// interrupted reports whether Interrupt was called, resetting it.
+func (b *BF) interrupted() bool {
+	return atomic.CompareAndSwapInt32(&b.intr, 1, 0)
+}
+
+// Continue executes commands until it reaches a breakpoint, or a watchpoint triggers.
+// It returns the position of the next command to run, which for a breakpoint is the
+// command the breakpoint is set on. It returns io.EOF if the program ends first.
+func (b *BF) Continue() (Position, error) {
+	return b.runUntil(-1)
+}
+
+// RunUntil executes commands until the next command to run is the one at offset,
+// a breakpoint is reached, or a watchpoint triggers. It returns the position of the
+// next command to run, or io.EOF if the program ends first.
+func (b *BF) RunUntil(offset int) (Position, error) {
+	return b.runUntil(offset)
+}
+
+func (b *BF) runUntil(offset int) (Position, error) {
+	start := time.Now()
+	defer func() { b.wall += time.Since(start) }()
+
+	if !b.observed() {
+		at, err := b.run(offset, true)
+		return b.position(at), err
+	}
+
+	// always run the current command, so a stopped program can move forward
+	at, err := b.next()
+	for err == nil {
+		next := b.peek(b.pc)
+		if len(b.hits) > 0 {
+			return b.position(next), nil
+		}
+		if next >= len(b.src) {
+			return b.position(next), io.EOF
+		}
+		if _, ok := b.bps[next]; ok || next == offset {
+			return b.position(next), nil
+		}
+		if b.interrupted() {
+			return b.position(next), ErrInterrupted
+		}
+
+		at, err = b.next()
+	}
+
+	return b.position(at), err
+}
+
+// Pos returns the position of the next command to run.
+func (b *BF) Pos() Position {
+	return b.position(b.peek(b.pc))
+}
+
+// Done reports whether there are no commands left to run.
+func (b *BF) Done() bool {
+	return b.peek(b.pc) >= len(b.src)
+}
+
+// SetBreakpoint sets a breakpoint on the command at offset. If there is no command
+// at offset, the breakpoint is set on the next command after it. It returns the
+// position of the breakpoint.
+func (b *BF) SetBreakpoint(offset int) (Position, error) {
+	if offset < 0 || offset >= len(b.src) {
+		return Position{}, ErrInvalidPosition
+	}
+
+	off := b.peek(offset)
+	if off >= len(b.src) || b.src[off] == 0 {
+		return Position{}, ErrInvalidPosition
+	}
+
+	b.bps[off] = struct{}{}
+	return b.position(off), nil
+}
+
+// SetBreakpointAt works like SetBreakpoint, taking a line and a column (both starting at 1)
+// instead of an offset.
+func (b *BF) SetBreakpointAt(line, column int) (Position, error) {
+	off, err := b.Offset(line, column)
+	if err != nil {
+		return Position{}, err
+	}
+
+	return b.SetBreakpoint(off)
+}
+
+// ClearBreakpoint removes the breakpoint of offset. Like SetBreakpoint, an offset
+// that is not on a command means the next command. It reports whether there was a
+// breakpoint to remove.
+func (b *BF) ClearBreakpoint(offset int) bool {
+	if offset < 0 || offset >= len(b.src) {
+		return false
+	}
+
+	off := b.peek(offset)
+	if _, ok := b.bps[off]; !ok {
+		return false
+	}
+	delete(b.bps, off)
+	return true
+}
+
+// Breakpoints returns the positions of the breakpoints, ordered by offset.
+func (b *BF) Breakpoints() []Position {
+	offs := make([]int, 0, len(b.bps))
+	for o := range b.bps {
+		offs = append(offs, o)
+	}
+	sort.Ints(offs)
+
+	ps := make([]Position, len(offs))
+	for i, o := range offs {
+		ps[i] = b.position(o)
+	}
+	return ps
+}
+
+// Offset converts a line and a column (both starting at 1) to a source offset.
+func (b *BF) Offset(line, column int) (int, error) {
+	if line < 1 || line > len(b.lines) || column < 1 {
+		return 0, ErrInvalidPosition
+	}
+
+	end := len(b.src)
+	if line < len(b.lines) {
+		end = b.lines[line]
+	}
+	off := b.lines[line-1] + column - 1
+	if off >= end {
+		return 0, ErrInvalidPosition
+	}
+
+	return off, nil
+}
+
+// Position converts a source offset to a Position.
+func (b *BF) Position(offset int) Position {
+	return b.position(offset)
+}
+
+// Pointer returns the data pointer, the index of the current cell.
+func (b *BF) Pointer() int {
+	return b.c
+}
+
+// Cell returns the value of the cell at index i. Cells that are not allocated yet are zero.
+func (b *BF) Cell(i int) int32 {
+	if i < 0 || i >= len(b.arr) {
+		return 0
+	}
+
+	return b.arr[i]
+}
+
+// Tape returns a copy of the allocated cells.
+func (b *BF) Tape() []int32 {
+	t := make([]int32, len(b.arr))
+	copy(t, b.arr)
+	return t
+}
+
+// LoopStack returns the positions of the loops being run, the innermost one last.
+func (b *BF) LoopStack() []Position {
+	ps := make([]Position, len(b.lpos))
+	for i, o := range b.lpos {
+		ps[i] = b.position(o)
+	}
+	return ps
+}
@@ -0,0 +1,226 @@
+package bf
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestBF_Step(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("one command at a time", func(t *testing.T) {
+		s := `++ comment
+>+`
+		var buf []byte
+		out := bytes.NewBuffer(buf)
+
+		bfi, err := New(strings.NewReader(s), out, nil)
+		c.Assert(err, qt.IsNil)
+
+		want := []Position{
+			{Offset: 0, Line: 1, Column: 1},
+			{Offset: 1, Line: 1, Column: 2},
+			{Offset: 11, Line: 2, Column: 1},
+			{Offset: 12, Line: 2, Column: 2},
+		}
+		for _, w := range want {
+			p, err := bfi.Step()
+			c.Assert(err, qt.IsNil)
+			c.Assert(p, qt.Equals, w)
+		}
+
+		_, err = bfi.Step()
+		c.Assert(err, qt.Equals, io.EOF)
+		c.Assert(bfi.Done(), qt.IsTrue)
+		c.Assert(bfi.Pointer(), qt.Equals, 1)
+		c.Assert(bfi.Cell(0), qt.Equals, int32(2))
+		c.Assert(bfi.Cell(1), qt.Equals, int32(1))
+	})
+
+	t.Run("skip loop on zero cell", func(t *testing.T) {
+		s := `[+++]+`
+		var buf []byte
+		out := bytes.NewBuffer(buf)
+
+		bfi, err := New(strings.NewReader(s), out, nil)
+		c.Assert(err, qt.IsNil)
+
+		p, err := bfi.Step()
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 0)
+		c.Assert(bfi.Pos().Offset, qt.Equals, 5)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+		c.Assert(bfi.Cell(0), qt.Equals, int32(1))
+	})
+
+	t.Run("loop stack", func(t *testing.T) {
+		s := `+[>+[-]<-]`
+		var buf []byte
+		out := bytes.NewBuffer(buf)
+
+		bfi, err := New(strings.NewReader(s), out, nil)
+		c.Assert(err, qt.IsNil)
+
+		p, err := bfi.RunUntil(5)
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 5)
+		c.Assert(bfi.LoopStack(), qt.DeepEquals, []Position{
+			{Offset: 1, Line: 1, Column: 2},
+			{Offset: 4, Line: 1, Column: 5},
+		})
+	})
+
+	t.Run("failed command", func(t *testing.T) {
+		s := `+[-]<+++`
+		bfi, err := New(strings.NewReader(s), io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+
+		_, err = bfi.RunUntil(4)
+		c.Assert(err, qt.IsNil)
+		p, err := bfi.Step()
+		c.Assert(err, qt.ErrorIs, ErrNegativeIndex)
+		c.Assert(p.Offset, qt.Equals, 4)
+
+		// the program stays on the '<' that failed
+		c.Assert(bfi.Pos().Offset, qt.Equals, 4)
+		p, err2 := bfi.Continue()
+		c.Assert(err2, qt.Equals, err)
+		c.Assert(p.Offset, qt.Equals, 4)
+		c.Assert(bfi.Exec(), qt.Equals, ErrNegativeIndex)
+		c.Assert(bfi.Cell(0), qt.Equals, int32(0))
+	})
+
+	t.Run("failed input", func(t *testing.T) {
+		s := `+,+`
+		bfi, err := New(strings.NewReader(s), io.Discard, strings.NewReader("x\n"))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.ErrorMatches, `invalid input: .*`)
+		c.Assert(bfi.Exec().Error(), qt.Equals, err.Error())
+		c.Assert(bfi.Cell(0), qt.Equals, int32(1))
+	})
+}
+
+func TestBF_Breakpoints(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("continue to breakpoints", func(t *testing.T) {
+		s := `+++[
+>+<-
+]>.`
+		var buf []byte
+		out := bytes.NewBuffer(buf)
+
+		bfi, err := New(strings.NewReader(s), out, nil)
+		c.Assert(err, qt.IsNil)
+
+		bp, err := bfi.SetBreakpointAt(2, 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(bp, qt.Equals, Position{Offset: 6, Line: 2, Column: 2})
+
+		for i := 0; i < 3; i++ {
+			p, err := bfi.Continue()
+			c.Assert(err, qt.IsNil)
+			c.Assert(p, qt.Equals, bp)
+			c.Assert(bfi.Cell(1), qt.Equals, int32(i))
+		}
+
+		_, err = bfi.Continue()
+		c.Assert(err, qt.Equals, io.EOF)
+		c.Assert(out.Bytes(), qt.ContentEquals, []byte{3})
+	})
+
+	t.Run("breakpoint on a comment", func(t *testing.T) {
+		s := `+ set cell
+-`
+		bfi, err := New(strings.NewReader(s), io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+
+		bp, err := bfi.SetBreakpoint(3)
+		c.Assert(err, qt.IsNil)
+		c.Assert(bp.Offset, qt.Equals, 11)
+		c.Assert(bfi.Breakpoints(), qt.DeepEquals, []Position{bp})
+
+		// cleared from the comment too, like it was set
+		c.Assert(bfi.ClearBreakpoint(3), qt.IsTrue)
+		c.Assert(bfi.Breakpoints(), qt.HasLen, 0)
+		c.Assert(bfi.ClearBreakpoint(bp.Offset), qt.IsFalse)
+
+		_, err = bfi.SetBreakpointAt(3, 1)
+		c.Assert(err, qt.Equals, ErrInvalidPosition)
+	})
+}
+
+func TestBF_StepOver(t *testing.T) {
+	c := qt.New(t)
+
+	s := `++[>+++[>+<-]<-]>.`
+	bfi, err := New(strings.NewReader(s), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+
+	p, err := bfi.StepOver()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 1)
+	p, err = bfi.StepOver()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 2)
+
+	// the whole outer loop
+	p, err = bfi.StepOver()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 16)
+	c.Assert(bfi.Cell(2), qt.Equals, int32(6))
+
+	// stops on a breakpoint inside a loop
+	bfi, err = New(strings.NewReader(s), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+	_, err = bfi.SetBreakpoint(9)
+	c.Assert(err, qt.IsNil)
+	_, err = bfi.RunUntil(2)
+	c.Assert(err, qt.IsNil)
+	p, err = bfi.StepOver()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 9)
+}
+
+func TestBF_StepOut(t *testing.T) {
+	c := qt.New(t)
+
+	s := `++[>+++[>+<-]<-]>.`
+	bfi, err := New(strings.NewReader(s), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+
+	_, err = bfi.RunUntil(9)
+	c.Assert(err, qt.IsNil)
+	c.Assert(bfi.LoopStack(), qt.HasLen, 2)
+
+	p, err := bfi.StepOut()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 13)
+	c.Assert(bfi.LoopStack(), qt.HasLen, 1)
+	c.Assert(bfi.Cell(2), qt.Equals, int32(3))
+
+	p, err = bfi.StepOut()
+	c.Assert(err, qt.IsNil)
+	c.Assert(p.Offset, qt.Equals, 16)
+	c.Assert(bfi.LoopStack(), qt.HasLen, 0)
+}
+
+func TestBF_Interrupt(t *testing.T) {
+	c := qt.New(t)
+
+	bfi, err := New(strings.NewReader(`+[]`), io.Discard, nil)
+	c.Assert(err, qt.IsNil)
+
+	go bfi.Interrupt()
+	_, err = bfi.Continue()
+	c.Assert(err, qt.Equals, ErrInterrupted)
+	c.Assert(bfi.LoopStack(), qt.HasLen, 1)
+}
@@ -0,0 +1,117 @@
+package bf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TraceEvent is a command run by a BF, as recorded by WithTrace.
+type TraceEvent struct {
+	Step   int64  `json:"step"` // number of the command in the run, starting at 1
+	Offset int    `json:"offset"`
+	Line   int    `json:"line"`
+	Column int    `json:"col"`
+	Op     string `json:"op"`
+	Ptr    int    `json:"ptr"`           // data pointer after the command
+	Cell   int32  `json:"cell"`          // value of the current cell after the command
+	Out    *int32 `json:"out,omitempty"` // value written by '.'
+	// Orig is the position in the original source, with WithSourceMap.
+	Orig *SourcePosition `json:"orig,omitempty"`
+}
+
+// TraceFilter selects the commands recorded by WithTrace. The zero value records all of them.
+type TraceFilter struct {
+	// Every records one command out of Every, starting with the first one in range.
+	// Zero and one record all of them.
+	Every int64
+	// FromStep and ToStep limit the recorded commands to an inclusive range of steps.
+	// Zero means no limit.
+	FromStep, ToStep int64
+	// FromLine and ToLine limit the recorded commands to an inclusive range of source
+	// lines. Zero means no limit.
+	FromLine, ToLine int
+}
+
+// match reports whether the command at pos, run as the step-th command, is recorded.
+func (f TraceFilter) match(step int64, pos Position) bool {
+	if step < f.FromStep || (f.ToStep > 0 && step > f.ToStep) {
+		return false
+	}
+	if pos.Line < f.FromLine || (f.ToLine > 0 && pos.Line > f.ToLine) {
+		return false
+	}
+	if f.Every > 1 {
+		from := f.FromStep
+		if from < 1 {
+			from = 1
+		}
+		return (step-from)%f.Every == 0
+	}
+	return true
+}
+
+// tracer writes the trace of a run.
+type tracer struct {
+	enc    *json.Encoder
+	filter TraceFilter
+}
+
+// WithTrace writes each command run to w as a TraceEvent in JSON, one per line (JSON Lines),
+// keeping the ones selected by filter. Writing to w is not buffered. A failed write stops
+// the program with an error.
+func WithTrace(w io.Writer, filter TraceFilter) Option {
+	return func(b *BF) {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false) // keep '<' and '>' readable
+		b.trace = &tracer{enc: enc, filter: filter}
+	}
+}
+
+// record writes the event of the command at pos, if the filter selects it.
+func (t *tracer) record(b *BF, pos Position, ch rune) error {
+	if !t.filter.match(b.steps, pos) {
+		return nil
+	}
+
+	e := b.event(pos, ch)
+	err := t.enc.Encode(&e)
+	if err != nil {
+		return fmt.Errorf("failed writing the trace: %w", err)
+	}
+	return nil
+}
+
+// event returns the event of the command ch at pos, which just ran.
+func (b *BF) event(pos Position, ch rune) TraceEvent {
+	e := TraceEvent{
+		Step:   b.steps,
+		Offset: pos.Offset,
+		Line:   pos.Line,
+		Column: pos.Column,
+		Op:     string(ch),
+		Ptr:    b.c,
+		Cell:   b.arr[b.c],
+		Orig:   pos.Orig,
+	}
+	if ch == '.' {
+		v := b.arr[b.c]
+		e.Out = &v
+	}
+
+	return e
+}
+
+// String returns the event in the "step N at line:col: op ptr=P cell=V" form, followed by
+// " out=V" for '.'. The original position follows the position, as in "1:5 (a.bf:2:1)".
+func (e TraceEvent) String() string {
+	pos := fmt.Sprintf("%d:%d", e.Line, e.Column)
+	if e.Orig != nil {
+		pos += fmt.Sprintf(" (%s)", e.Orig)
+	}
+	s := fmt.Sprintf("step %d at %s: %s ptr=%d cell=%d", e.Step, pos, e.Op, e.Ptr, e.Cell)
+	if e.Out != nil {
+		s += fmt.Sprintf(" out=%d", *e.Out)
+	}
+	return s
+}
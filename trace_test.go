@@ -0,0 +1,108 @@
+package bf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// readTrace decodes the events of a trace
+func readTrace(c *qt.C, r io.Reader) []TraceEvent {
+	var events []TraceEvent
+	dec := json.NewDecoder(r)
+	for {
+		var e TraceEvent
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return events
+		}
+		c.Assert(err, qt.IsNil)
+		events = append(events, e)
+	}
+}
+
+func TestWithTrace(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("events", func(t *testing.T) {
+		var trace bytes.Buffer
+		bfi, err := New(strings.NewReader("++ two\n>+<[-]."), io.Discard, nil, WithTrace(&trace, TraceFilter{}))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.IsNil)
+
+		events := readTrace(c, &trace)
+		c.Assert(events, qt.HasLen, 11)
+		c.Assert(events[0], qt.DeepEquals, TraceEvent{Step: 1, Offset: 0, Line: 1, Column: 1, Op: "+", Ptr: 0, Cell: 1})
+		c.Assert(events[2], qt.DeepEquals, TraceEvent{Step: 3, Offset: 7, Line: 2, Column: 1, Op: ">", Ptr: 1, Cell: 0})
+
+		out := int32(0)
+		c.Assert(events[10], qt.DeepEquals, TraceEvent{Step: 11, Offset: 13, Line: 2, Column: 7, Op: ".", Ptr: 0, Cell: 0, Out: &out})
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			filter TraceFilter
+			steps  []int64
+		}{
+			{"every", TraceFilter{Every: 4}, []int64{1, 5, 9}},
+			{"steps", TraceFilter{FromStep: 3, ToStep: 5}, []int64{3, 4, 5}},
+			{"sampled steps", TraceFilter{Every: 2, FromStep: 4}, []int64{4, 6, 8, 10}},
+			{"lines", TraceFilter{ToLine: 1}, []int64{1, 2}},
+			{"lines and steps", TraceFilter{FromLine: 2, ToStep: 4}, []int64{3, 4}},
+		}
+		for _, tt := range tests {
+			var trace bytes.Buffer
+			bfi, err := New(strings.NewReader("++\n>+<[-]"), io.Discard, nil, WithTrace(&trace, tt.filter))
+			c.Assert(err, qt.IsNil)
+			err = bfi.Exec()
+			c.Assert(err, qt.IsNil)
+
+			var steps []int64
+			for _, e := range readTrace(c, &trace) {
+				steps = append(steps, e.Step)
+			}
+			c.Assert(steps, qt.DeepEquals, tt.steps, qt.Commentf(tt.name))
+		}
+	})
+
+	t.Run("step back", func(t *testing.T) {
+		var trace bytes.Buffer
+		bfi, err := New(strings.NewReader("+++"), io.Discard, nil, WithTrace(&trace, TraceFilter{}), WithHistory(0))
+		c.Assert(err, qt.IsNil)
+
+		_, err = bfi.Step()
+		c.Assert(err, qt.IsNil)
+		_, err = bfi.Step()
+		c.Assert(err, qt.IsNil)
+		_, err = bfi.StepBack()
+		c.Assert(err, qt.IsNil)
+		_, err = bfi.Step()
+		c.Assert(err, qt.IsNil)
+
+		events := readTrace(c, &trace)
+		c.Assert(events[2].Step, qt.Equals, int64(2))
+		c.Assert(events[2].Cell, qt.Equals, int32(2))
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		bfi, err := New(strings.NewReader("+"), io.Discard, nil, WithTrace(failWriter{}, TraceFilter{}))
+		c.Assert(err, qt.IsNil)
+
+		err = bfi.Exec()
+		c.Assert(err, qt.ErrorMatches, "failed writing the trace: write failed")
+	})
+}
+
+type failWriter struct{}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
@@ -0,0 +1,184 @@
+package bf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTraceMismatch is returned by DiffTraces when the traces do not record the same steps,
+// e.g. because they were written with different filters.
+var ErrTraceMismatch = errors.New("traces do not record the same steps")
+
+// Divergence is the first step where two runs differ.
+type Divergence struct {
+	Step int64
+	// Field is what differs: "ptr", "cell", "out", "end" when a run ends first, or
+	// "error" when the command of a run fails, with CompareRuns.
+	Field string
+	// A and B are the events of the step in each run, nil for a run that ended. The
+	// event of a command that failed holds the pointer and the cell it failed on.
+	A, B *TraceEvent
+	// ErrA and ErrB are the errors of the commands of the step, when Field is "error".
+	ErrA, ErrB error
+	// ContextA and ContextB are the events right before the step, the oldest first.
+	ContextA, ContextB []TraceEvent
+}
+
+// String returns a one line description of the divergence.
+func (d *Divergence) String() string {
+	switch d.Field {
+	case "end":
+		ended := "a"
+		if d.B == nil {
+			ended = "b"
+		}
+		return fmt.Sprintf("runs diverge at step %d: run %s ended", d.Step, ended)
+	case "error":
+		failed, err := "a", d.ErrA
+		if err == nil {
+			failed, err = "b", d.ErrB
+		}
+		return fmt.Sprintf("runs diverge at step %d: run %s failed: %v", d.Step, failed, err)
+	}
+	return fmt.Sprintf("runs diverge at step %d: %s differs", d.Step, d.Field)
+}
+
+// differ compares two runs event by event, keeping the last events as context.
+type differ struct {
+	context            int
+	contextA, contextB []TraceEvent
+}
+
+// compare returns the divergence of the events a and b of the same step, or nil if they
+// match. A nil event is a run that ended, and a and b are not both nil.
+func (d *differ) compare(a, b *TraceEvent) *Divergence {
+	field := ""
+	switch {
+	case a == nil || b == nil:
+		field = "end"
+	case a.Ptr != b.Ptr:
+		field = "ptr"
+	case a.Cell != b.Cell:
+		field = "cell"
+	case (a.Out == nil) != (b.Out == nil) || (a.Out != nil && *a.Out != *b.Out):
+		field = "out"
+	}
+
+	if field == "" {
+		d.contextA = d.push(d.contextA, *a)
+		d.contextB = d.push(d.contextB, *b)
+		return nil
+	}
+	return d.divergence(field, a, b)
+}
+
+// divergence returns the divergence of field at the events a and b, which are not both nil.
+func (d *differ) divergence(field string, a, b *TraceEvent) *Divergence {
+	div := &Divergence{Field: field, A: a, B: b, ContextA: d.contextA, ContextB: d.contextB}
+	if a != nil {
+		div.Step = a.Step
+	} else {
+		div.Step = b.Step
+	}
+	return div
+}
+
+// push appends e to events, keeping at most the context size.
+func (d *differ) push(events []TraceEvent, e TraceEvent) []TraceEvent {
+	if d.context <= 0 {
+		return nil
+	}
+	if len(events) == d.context {
+		copy(events, events[1:])
+		events = events[:len(events)-1]
+	}
+	return append(events, e)
+}
+
+// DiffTraces compares two traces written by WithTrace, and returns the first step where the
+// data pointer, the current cell or the output differ, with up to context events before it.
+// It returns nil if the traces match. Both traces must be written with the same filter.
+func DiffTraces(a, b io.Reader, context int) (*Divergence, error) {
+	d := &differ{context: context}
+	da, db := json.NewDecoder(a), json.NewDecoder(b)
+
+	for {
+		ea, err := nextEvent(da)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading trace a: %w", err)
+		}
+		eb, err := nextEvent(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading trace b: %w", err)
+		}
+		if ea == nil && eb == nil {
+			return nil, nil
+		}
+		if ea != nil && eb != nil && ea.Step != eb.Step {
+			return nil, fmt.Errorf("%w: step %d in a, step %d in b", ErrTraceMismatch, ea.Step, eb.Step)
+		}
+
+		if div := d.compare(ea, eb); div != nil {
+			return div, nil
+		}
+	}
+}
+
+// nextEvent decodes the next event of a trace, or returns nil at its end.
+func nextEvent(dec *json.Decoder) (*TraceEvent, error) {
+	var e TraceEvent
+	err := dec.Decode(&e)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// CompareRuns runs a and b side by side, one command at a time, and returns the first step
+// where the data pointer, the current cell or the output differ, with up to context events
+// before it. When the command of only one run fails, or they fail with different errors,
+// that step is the divergence, with the "error" field. It returns nil if both runs end
+// without diverging, and an error if both fail at the same step with the same error. a
+// and b can run different sources, or the same source with different options.
+func CompareRuns(a, b *BF, context int) (*Divergence, error) {
+	d := &differ{context: context}
+
+	for {
+		ea, errA := a.stepEvent()
+		eb, errB := b.stepEvent()
+		if errA != nil && errB != nil && errA.Error() == errB.Error() {
+			return nil, fmt.Errorf("run a failed: %w", errA)
+		}
+		if errA != nil || errB != nil {
+			div := d.divergence("error", ea, eb)
+			div.ErrA, div.ErrB = errA, errB
+			return div, nil
+		}
+		if ea == nil && eb == nil {
+			return nil, nil
+		}
+
+		if div := d.compare(ea, eb); div != nil {
+			return div, nil
+		}
+	}
+}
+
+// stepEvent runs the next command and returns its event, or nil once there are no
+// commands left. The event of a command that fails is returned with its error.
+func (b *BF) stepEvent() (*TraceEvent, error) {
+	step := b.steps + 1
+	p, err := b.Step()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+
+	e := b.event(p, rune(b.src[p.Offset]))
+	e.Step = step
+	return &e, err
+}
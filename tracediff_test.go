@@ -0,0 +1,113 @@
+package bf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// traceOf runs src and returns its trace
+func traceOf(c *qt.C, src string, filter TraceFilter) *bytes.Buffer {
+	var trace bytes.Buffer
+	bfi, err := New(strings.NewReader(src), io.Discard, nil, WithTrace(&trace, filter))
+	c.Assert(err, qt.IsNil)
+	err = bfi.Exec()
+	c.Assert(err, qt.IsNil)
+	return &trace
+}
+
+func TestDiffTraces(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		name    string
+		a, b    string
+		step    int64
+		field   string
+		context int
+	}{
+		{"same", "++[-]", "++[-]", 0, "", 0},
+		{"same effect", "++[-]", "++ comment [-]", 0, "", 0},
+		{"cell", "+++-", "++++", 4, "cell", 3},
+		{"ptr", "++>>", "++><", 4, "ptr", 3},
+		{"out", "+.", "+[-]", 2, "out", 1},
+		{"end", "++", "++>", 3, "end", 2},
+	}
+	for _, tt := range tests {
+		div, err := DiffTraces(traceOf(c, tt.a, TraceFilter{}), traceOf(c, tt.b, TraceFilter{}), 5)
+		c.Assert(err, qt.IsNil)
+		if tt.field == "" {
+			c.Assert(div, qt.IsNil, qt.Commentf(tt.name))
+			continue
+		}
+
+		c.Assert(div, qt.Not(qt.IsNil), qt.Commentf(tt.name))
+		c.Assert(div.Step, qt.Equals, tt.step, qt.Commentf(tt.name))
+		c.Assert(div.Field, qt.Equals, tt.field, qt.Commentf(tt.name))
+		c.Assert(div.ContextA, qt.HasLen, tt.context, qt.Commentf(tt.name))
+	}
+
+	t.Run("context size", func(t *testing.T) {
+		div, err := DiffTraces(traceOf(c, "++++++-", TraceFilter{}), traceOf(c, "+++++++", TraceFilter{}), 2)
+		c.Assert(err, qt.IsNil)
+		c.Assert(div.String(), qt.Equals, "runs diverge at step 7: cell differs")
+		c.Assert(div.ContextB, qt.HasLen, 2)
+		c.Assert(div.ContextB[0].Step, qt.Equals, int64(5))
+		c.Assert(div.A.Cell, qt.Equals, int32(5))
+		c.Assert(div.B.Cell, qt.Equals, int32(7))
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		_, err := DiffTraces(traceOf(c, "+++", TraceFilter{Every: 2}), traceOf(c, "+++", TraceFilter{}), 2)
+		c.Assert(errors.Is(err, ErrTraceMismatch), qt.IsTrue)
+	})
+}
+
+func TestCompareRuns(t *testing.T) {
+	c := qt.New(t)
+
+	newBF := func(src string, out io.Writer) *BF {
+		bfi, err := New(strings.NewReader(src), out, nil)
+		c.Assert(err, qt.IsNil)
+		return bfi
+	}
+
+	var outA, outB bytes.Buffer
+	div, err := CompareRuns(newBF("++++++++[>++++++++<-]>+.", &outA), newBF("++++++++[>++++++++<-]>+[-]", &outB), 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(div.String(), qt.Equals, "runs diverge at step 108: out differs")
+	c.Assert(div.A.String(), qt.Equals, "step 108 at 1:24: . ptr=1 cell=65 out=65")
+	c.Assert(div.B.String(), qt.Equals, "step 108 at 1:24: [ ptr=1 cell=65")
+	c.Assert(div.ContextA, qt.HasLen, 1)
+	c.Assert(outA.String(), qt.Equals, "A")
+
+	div, err = CompareRuns(newBF("+[-]", io.Discard), newBF("+[-]", io.Discard), 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(div, qt.IsNil)
+
+	_, err = CompareRuns(newBF("+[<]", io.Discard), newBF("+[<]", io.Discard), 1)
+	c.Assert(err, qt.ErrorMatches, "run a failed: array index can't be less than zero at 1:3 \\(line:column\\)")
+
+	// only one run fails
+	div, err = CompareRuns(newBF("+[<]", io.Discard), newBF("+[-]", io.Discard), 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(div.Field, qt.Equals, "error")
+	c.Assert(div.ErrA, qt.ErrorIs, ErrNegativeIndex)
+	c.Assert(div.ErrB, qt.IsNil)
+	c.Assert(div.String(), qt.Equals, "runs diverge at step 3: run a failed: array index can't be less than zero at 1:3 (line:column)")
+	c.Assert(div.A.String(), qt.Equals, "step 3 at 1:3: < ptr=0 cell=1")
+	c.Assert(div.B.String(), qt.Equals, "step 3 at 1:3: - ptr=0 cell=0")
+	c.Assert(div.ContextA, qt.DeepEquals, []TraceEvent{{Step: 2, Offset: 1, Line: 1, Column: 2, Op: "[", Cell: 1}})
+
+	// a run fails where the other ends
+	div, err = CompareRuns(newBF("+>", io.Discard), newBF("+>\x00", io.Discard), 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(div.Field, qt.Equals, "error")
+	c.Assert(div.Step, qt.Equals, int64(3))
+	c.Assert(div.A, qt.IsNil)
+	c.Assert(div.ErrB, qt.ErrorIs, ErrIllegalCharNul)
+}
@@ -0,0 +1,268 @@
+package bf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidWatchpoint is returned when a watchpoint expression can not be parsed.
+var ErrInvalidWatchpoint = errors.New("invalid watchpoint")
+
+// WatchKind is the kind of event a Watchpoint watches.
+type WatchKind int
+
+const (
+	// WatchCell watches writes to a cell.
+	WatchCell WatchKind = iota
+	// WatchPointer watches the data pointer entering a range of cells.
+	WatchPointer
+)
+
+// Op is the condition a WatchCell watchpoint checks on the new value of the cell.
+type Op int
+
+const (
+	OpWrite Op = iota // any write, even if the value does not change
+	OpEq              // ==
+	OpNe              // !=
+	OpLt              // <
+	OpGt              // >
+	OpLe              // <=
+	OpGe              // >=
+	OpIn              // in A..B, inclusive
+)
+
+var ops = []struct {
+	op  Op
+	tok string
+}{
+	// two character tokens first, so they are not taken for their prefix
+	{OpEq, "=="}, {OpNe, "!="}, {OpLe, "<="}, {OpGe, ">="}, {OpLt, "<"}, {OpGt, ">"}, {OpIn, "in "},
+}
+
+// Watchpoint stops Continue and RunUntil after the command that triggers it.
+//
+// A WatchCell watchpoint triggers when cell Cell is written. Unless Op is OpWrite, it
+// only triggers when the value changes to one that satisfies Op with A (and B for OpIn).
+// A WatchPointer watchpoint triggers when the data pointer moves into the range A..B
+// from outside of it.
+type Watchpoint struct {
+	ID   int // set by AddWatchpoint
+	Kind WatchKind
+	Cell int
+	Op   Op
+	A, B int
+}
+
+// WatchHit describes a watchpoint triggered by a command.
+type WatchHit struct {
+	Watchpoint Watchpoint
+	Pos        Position // position of the command that triggered the watchpoint
+	Old, New   int      // cell value, or data pointer for WatchPointer, around the command
+}
+
+// String formats the hit for humans, e.g. "cell[5]==0 at 2:4: 1 -> 0".
+func (h WatchHit) String() string {
+	return fmt.Sprintf("%s at %s: %d -> %d", h.Watchpoint, h.Pos, h.Old, h.New)
+}
+
+// ParseWatchpoint parses a watchpoint expression. Supported forms are
+//
+//	cell[N]            any write to cell N
+//	cell[N]==V         also !=, <, >, <= and >=
+//	cell[N] in A..B    value in the inclusive range
+//	ptr in A..B        data pointer entering the inclusive range
+//	ptr==N             data pointer reaching cell N
+func ParseWatchpoint(expr string) (Watchpoint, error) {
+	s := strings.TrimSpace(expr)
+	var w Watchpoint
+	var rest string
+
+	switch {
+	case strings.HasPrefix(s, "cell["):
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return w, fmt.Errorf("%w %q: missing ]", ErrInvalidWatchpoint, expr)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(s[len("cell["):end]))
+		if err != nil || n < 0 {
+			return w, fmt.Errorf("%w %q: bad cell index", ErrInvalidWatchpoint, expr)
+		}
+		w.Kind, w.Cell = WatchCell, n
+		rest = s[end+1:]
+	case strings.HasPrefix(s, "ptr"):
+		w.Kind = WatchPointer
+		rest = s[len("ptr"):]
+	default:
+		return w, fmt.Errorf("%w %q: want cell[N] or ptr", ErrInvalidWatchpoint, expr)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		if w.Kind == WatchPointer {
+			return w, fmt.Errorf("%w %q: missing condition", ErrInvalidWatchpoint, expr)
+		}
+		return w, nil
+	}
+
+	var op Op = -1
+	for _, o := range ops {
+		if strings.HasPrefix(rest, o.tok) {
+			op = o.op
+			rest = rest[len(o.tok):]
+			break
+		}
+	}
+	if op == -1 {
+		return w, fmt.Errorf("%w %q: unknown condition", ErrInvalidWatchpoint, expr)
+	}
+
+	if op == OpIn {
+		a, b, ok := strings.Cut(rest, "..")
+		if !ok {
+			return w, fmt.Errorf("%w %q: want A..B", ErrInvalidWatchpoint, expr)
+		}
+		var errA, errB error
+		w.A, errA = strconv.Atoi(strings.TrimSpace(a))
+		w.B, errB = strconv.Atoi(strings.TrimSpace(b))
+		if errA != nil || errB != nil || w.A > w.B {
+			return w, fmt.Errorf("%w %q: bad range", ErrInvalidWatchpoint, expr)
+		}
+	} else {
+		v, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil {
+			return w, fmt.Errorf("%w %q: bad value", ErrInvalidWatchpoint, expr)
+		}
+		w.A = v
+	}
+
+	if w.Kind == WatchPointer {
+		switch op {
+		case OpIn:
+		case OpEq:
+			w.B = w.A
+		default:
+			return w, fmt.Errorf("%w %q: ptr supports == and in", ErrInvalidWatchpoint, expr)
+		}
+		return w, nil
+	}
+
+	w.Op = op
+	return w, nil
+}
+
+// String formats the watchpoint in the form ParseWatchpoint accepts.
+func (w Watchpoint) String() string {
+	if w.Kind == WatchPointer {
+		if w.A == w.B {
+			return fmt.Sprintf("ptr==%d", w.A)
+		}
+		return fmt.Sprintf("ptr in %d..%d", w.A, w.B)
+	}
+
+	cell := fmt.Sprintf("cell[%d]", w.Cell)
+	switch w.Op {
+	case OpWrite:
+		return cell
+	case OpIn:
+		return fmt.Sprintf("%s in %d..%d", cell, w.A, w.B)
+	}
+	for _, o := range ops[:len(ops)-1] {
+		if o.op == w.Op {
+			return fmt.Sprintf("%s%s%d", cell, o.tok, w.A)
+		}
+	}
+	return cell
+}
+
+// match reports whether the watchpoint triggers for a command that moved the data
+// pointer from ptr to nptr and, if wrote is true, changed the cell at ptr from old to v.
+func (w Watchpoint) match(ptr, nptr int, wrote bool, old, v int32) bool {
+	if w.Kind == WatchPointer {
+		in := func(p int) bool { return p >= w.A && p <= w.B }
+		return !in(ptr) && in(nptr)
+	}
+
+	if !wrote || w.Cell != ptr {
+		return false
+	}
+	if w.Op == OpWrite {
+		return true
+	}
+	if old == v {
+		return false
+	}
+
+	n := int(v)
+	switch w.Op {
+	case OpEq:
+		return n == w.A
+	case OpNe:
+		return n != w.A
+	case OpLt:
+		return n < w.A
+	case OpGt:
+		return n > w.A
+	case OpLe:
+		return n <= w.A
+	case OpGe:
+		return n >= w.A
+	case OpIn:
+		return n >= w.A && n <= w.B
+	}
+
+	return false
+}
+
+// AddWatchpoint registers w and returns its ID.
+func (b *BF) AddWatchpoint(w Watchpoint) int {
+	b.watchID++
+	w.ID = b.watchID
+	b.watches = append(b.watches, w)
+	return w.ID
+}
+
+// RemoveWatchpoint removes the watchpoint with the given ID, if any.
+func (b *BF) RemoveWatchpoint(id int) {
+	for i, w := range b.watches {
+		if w.ID == id {
+			b.watches = append(b.watches[:i], b.watches[i+1:]...)
+			return
+		}
+	}
+}
+
+// Watchpoints returns the registered watchpoints, ordered by ID.
+func (b *BF) Watchpoints() []Watchpoint {
+	ws := make([]Watchpoint, len(b.watches))
+	copy(ws, b.watches)
+	return ws
+}
+
+// WatchHits returns the watchpoints triggered by the last command run.
+func (b *BF) WatchHits() []WatchHit {
+	return b.hits
+}
+
+// watch checks the watchpoints for the command ch at pos, that moved the data pointer
+// from ptr to nptr, and changed the cell at ptr from old to v.
+func (b *BF) watch(pos Position, ch rune, ptr, nptr int, old, v int32) {
+	wrote := ch == '+' || ch == '-' || ch == ','
+	if _, ok := b.ucmds[ch]; ok {
+		wrote = true
+	}
+
+	for _, w := range b.watches {
+		if !w.match(ptr, nptr, wrote, old, v) {
+			continue
+		}
+
+		h := WatchHit{Watchpoint: w, Pos: pos, Old: int(old), New: int(v)}
+		if w.Kind == WatchPointer {
+			h.Old, h.New = ptr, nptr
+		}
+		b.hits = append(b.hits, h)
+	}
+}
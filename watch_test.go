@@ -0,0 +1,100 @@
+package bf
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestParseWatchpoint(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		expr string
+		want Watchpoint
+	}{
+		{"cell[3]", Watchpoint{Kind: WatchCell, Cell: 3, Op: OpWrite}},
+		{"cell[5]==0", Watchpoint{Kind: WatchCell, Cell: 5, Op: OpEq, A: 0}},
+		{"cell[5] != 2", Watchpoint{Kind: WatchCell, Cell: 5, Op: OpNe, A: 2}},
+		{"cell[1]<-1", Watchpoint{Kind: WatchCell, Cell: 1, Op: OpLt, A: -1}},
+		{"cell[1]>=10", Watchpoint{Kind: WatchCell, Cell: 1, Op: OpGe, A: 10}},
+		{"cell[2] in 10..20", Watchpoint{Kind: WatchCell, Cell: 2, Op: OpIn, A: 10, B: 20}},
+		{"ptr in 4..8", Watchpoint{Kind: WatchPointer, A: 4, B: 8}},
+		{"ptr==7", Watchpoint{Kind: WatchPointer, A: 7, B: 7}},
+	}
+	for _, tt := range tests {
+		w, err := ParseWatchpoint(tt.expr)
+		c.Assert(err, qt.IsNil, qt.Commentf(tt.expr))
+		c.Assert(w, qt.Equals, tt.want, qt.Commentf(tt.expr))
+
+		again, err := ParseWatchpoint(w.String())
+		c.Assert(err, qt.IsNil)
+		c.Assert(again, qt.Equals, w)
+	}
+
+	for _, expr := range []string{"", "cell[", "cell[x]", "cell[1]=1", "ptr", "ptr<3", "cell[1] in 3..1"} {
+		_, err := ParseWatchpoint(expr)
+		c.Assert(err, qt.ErrorIs, ErrInvalidWatchpoint, qt.Commentf(expr))
+	}
+}
+
+func TestBF_Watchpoints(t *testing.T) {
+	c := qt.New(t)
+
+	t.Run("cell condition", func(t *testing.T) {
+		s := `+++[>+<-]>[-]`
+		bfi, err := New(strings.NewReader(s), io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+
+		w, err := ParseWatchpoint("cell[1]==2")
+		c.Assert(err, qt.IsNil)
+		bfi.AddWatchpoint(w)
+
+		p, err := bfi.Continue()
+		c.Assert(err, qt.IsNil)
+		c.Assert(p.Offset, qt.Equals, 6)
+		hits := bfi.WatchHits()
+		c.Assert(hits, qt.HasLen, 1)
+		c.Assert(hits[0].String(), qt.Equals, "cell[1]==2 at 1:6: 1 -> 2")
+
+		// the loop at the end brings the cell back to zero, passing 2 again
+		_, err = bfi.Continue()
+		c.Assert(err, qt.IsNil)
+		c.Assert(bfi.WatchHits()[0].Pos.Offset, qt.Equals, 11)
+
+		_, err = bfi.Continue()
+		c.Assert(err, qt.Equals, io.EOF)
+	})
+
+	t.Run("writes and pointer", func(t *testing.T) {
+		s := `>>+<<+>>>`
+		bfi, err := New(strings.NewReader(s), io.Discard, nil)
+		c.Assert(err, qt.IsNil)
+
+		id := bfi.AddWatchpoint(Watchpoint{Kind: WatchCell, Cell: 0})
+		bfi.AddWatchpoint(Watchpoint{Kind: WatchPointer, A: 2, B: 5})
+		c.Assert(bfi.Watchpoints(), qt.HasLen, 2)
+
+		var got []string
+		for {
+			_, err := bfi.Continue()
+			if err == io.EOF {
+				break
+			}
+			c.Assert(err, qt.IsNil)
+			for _, h := range bfi.WatchHits() {
+				got = append(got, h.String())
+			}
+		}
+		c.Assert(got, qt.DeepEquals, []string{
+			"ptr in 2..5 at 1:2: 1 -> 2",
+			"cell[0] at 1:6: 0 -> 1",
+			"ptr in 2..5 at 1:8: 1 -> 2",
+		})
+
+		bfi.RemoveWatchpoint(id)
+		c.Assert(bfi.Watchpoints(), qt.HasLen, 1)
+	})
+}